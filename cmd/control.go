@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var controlCmd = &cobra.Command{
+	Use:   "control [socket] [command] [args...]",
+	Short: "Send a command to a running conversion",
+	Long: `Send a command to the control socket of a running conversion started
+with --control-socket. Commands: status, queue, pause, resume,
+promote <path>, concurrency <n>, cancel <path>.`,
+	Args:          cobra.MinimumNArgs(2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := net.DialTimeout("unix", args[0], 5*time.Second)
+		if err != nil {
+			return fmt.Errorf("failed to reach the conversion: %w", err)
+		}
+		defer conn.Close()
+
+		if _, err := fmt.Fprintln(conn, strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+		reply, err := io.ReadAll(conn)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, string(reply))
+		if strings.HasPrefix(string(reply), "error:") {
+			return fmt.Errorf("command failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(controlCmd)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/kevindurb/media-converter/internal/config"
 	"github.com/kevindurb/media-converter/internal/converter"
 	"github.com/kevindurb/media-converter/internal/logger"
+	"github.com/kevindurb/media-converter/internal/server"
 	"github.com/kevindurb/media-converter/internal/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -30,6 +31,10 @@ and videos to efficient codecs (H.265, AV1) with built-in safety checks and file
 		cfg.SourceDir = args[0]
 		cfg.DestDir = args[1]
 
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+
 		// Validate directories
 		if _, err := os.Stat(cfg.SourceDir); os.IsNotExist(err) {
 			return fmt.Errorf("source directory does not exist: %s", cfg.SourceDir)
@@ -64,6 +69,24 @@ and videos to efficient codecs (H.265, AV1) with built-in safety checks and file
 		// Initialize converter
 		conv := converter.NewConverter(cfg, log)
 
+		// Status API and web dashboard
+		if cfg.HTTPAddr != "" {
+			srv := server.New(cfg.HTTPAddr, conv, log)
+			if err := srv.Start(); err != nil {
+				return fmt.Errorf("failed to start HTTP server: %w", err)
+			}
+			defer srv.Shutdown()
+		}
+
+		// Queue commands on a unix socket
+		if cfg.ControlSocket != "" {
+			control := server.NewControl(cfg.ControlSocket, conv, log)
+			if err := control.Start(); err != nil {
+				return fmt.Errorf("failed to start control socket: %w", err)
+			}
+			defer control.Shutdown()
+		}
+
 		// Run conversion
 		return conv.Convert()
 	},
@@ -86,6 +109,11 @@ func init() {
 	rootCmd.Flags().BoolP("dry-run", "n", false, "Show what would be converted without actually converting")
 	rootCmd.Flags().BoolP("keep-originals", "k", true, "Keep original files after conversion")
 	rootCmd.Flags().IntP("jobs", "j", 0, "Number of parallel jobs (default: CPU cores - 1)")
+	rootCmd.Flags().Bool("tui", false, "Show a live dashboard of running jobs (plain log lines when output is not a terminal)")
+	rootCmd.Flags().String("http", "", "Serve the status API and web dashboard on this address (e.g. 127.0.0.1:8080; a bare :8080 also binds 127.0.0.1, use 0.0.0.0:8080 for every interface)")
+	rootCmd.Flags().String("control-socket", "", "Accept queue commands (pause, resume, promote, concurrency) on this unix socket")
+	rootCmd.Flags().StringSlice("run-window", nil, "Only start files inside this daily window, e.g. 22:00-07:00 or 22:00-07:00@max (repeatable)")
+	rootCmd.Flags().String("outside-window", "finish", "Running files outside the run windows: finish or suspend")
 
 	// Image conversion flags
 	rootCmd.Flags().String("photo-format", "avif", "Output format for photos (avif, webp)")
@@ -94,8 +122,46 @@ func init() {
 
 	// Video conversion flags
 	rootCmd.Flags().String("video-codec", "h265", "Video codec (h265, h264, av1)")
+	rootCmd.Flags().String("video-container", "mp4", "Video container (mp4, mkv, webm); mkv and webm use Opus audio")
+	rootCmd.Flags().String("video-mode", "crf", "Video rate control (crf, cq, 2pass)")
+	rootCmd.Flags().String("video-max-bitrate", "", "Bitrate ceiling for cq mode (e.g. 8M)")
+	rootCmd.Flags().String("video-target-bitrate", "", "Average video bitrate for 2pass mode (e.g. 4M)")
+	rootCmd.Flags().Float64("video-target-size", 0, "Target file size in MB for 2pass mode (overrides --video-target-bitrate)")
 	rootCmd.Flags().Int("video-crf", 28, "Video CRF value (lower = better quality)")
 	rootCmd.Flags().Bool("video-acceleration", true, "Enable hardware acceleration for video conversion")
+	rootCmd.Flags().String("video-hw-backend", "auto", "Hardware encoder backend (auto, videotoolbox, nvenc, qsv, vaapi)")
+	rootCmd.Flags().String("av1-encoder", "auto", "AV1 encoder (auto, libsvtav1, libaom-av1, librav1e)")
+	rootCmd.Flags().String("av1-speed", "medium", "AV1 encoding speed (fast, medium, slow)")
+	rootCmd.Flags().Int("av1-film-grain", 0, "AV1 film-grain synthesis strength (0 disables, up to 50)")
+
+	// RAW development flags
+	rootCmd.Flags().String("raw-backend", "magick", "RAW development backend (magick, dcraw_emu, darktable, rawtherapee, embedded)")
+	rootCmd.Flags().String("raw-white-balance", "camera", "RAW white balance (camera, auto)")
+	rootCmd.Flags().Float64("raw-exposure", 0.0, "RAW exposure compensation in EV")
+	rootCmd.Flags().String("raw-color-profile", "srgb", "RAW output colour profile (srgb, adobe, prophoto, p3)")
+
+	// Colour management flags
+	rootCmd.Flags().String("color-mode", "preserve", "Photo colour handling: preserve the source ICC profile or convert to --color-target")
+	rootCmd.Flags().String("color-target", "srgb", "Target colour profile for convert mode (srgb, p3 or path to an ICC file)")
+
+	// HDR flags
+	rootCmd.Flags().String("hdr", "preserve", "HDR video handling: preserve (10-bit PQ/HLG) or tonemap (convert to SDR)")
+
+	// Rotation flags
+	rootCmd.Flags().String("rotation", "bake", "Rotated videos: bake (rotate the pixels upright) or metadata (keep the display matrix)")
+
+	// Interlaced video flags
+	rootCmd.Flags().String("deinterlace", "auto", "Interlaced video detection: auto (field order, idet when unknown), idet (always sample), off")
+	rootCmd.Flags().String("deinterlace-filter", "bwdif", "Deinterlacing filter (bwdif, yadif)")
+	rootCmd.Flags().String("deinterlace-rate", "field", "Deinterlaced frame rate: field (50i -> 50p) or frame (50i -> 25p)")
+
+	// Frame rate flags
+	rootCmd.Flags().String("fps-mode", "auto", "Frame timing: auto (keep VFR/high-speed timestamps), passthrough (always), conform (constant --fps-conform rate)")
+	rootCmd.Flags().Float64("fps-conform", 30, "Constant frame rate for --fps-mode conform")
+
+	// Animated image flags
+	rootCmd.Flags().String("animated", "image", "Handling of animated GIF/WebP/APNG (image: animated AVIF/WebP, video: looping MP4, flatten: first frame only)")
+	rootCmd.Flags().String("animated-video-codec", "h265", "Video codec for animated images in video mode (h265, av1)")
 
 	// Adaptive worker flags
 	rootCmd.Flags().Bool("adaptive-workers", false, "Enable adaptive worker management for video conversions")
@@ -106,6 +172,25 @@ func init() {
 	rootCmd.Flags().Float64("adaptive-workers-mem-low", 20.0, "Minimum available memory percentage before reducing workers")
 	rootCmd.Flags().Int("adaptive-workers-interval", 3, "Seconds between adaptive worker checks")
 
+	// Encoder process priority flags
+	rootCmd.Flags().Int("nice", 0, "CPU niceness of the encoder processes (-20 to 19)")
+	rootCmd.Flags().String("io-class", "none", "I/O scheduling class of the encoder processes on Linux (none, idle, best-effort, realtime)")
+	rootCmd.Flags().Float64("cpu-limit", 0, "Cores shared by all encoder processes, enforced with a cgroup v2 on Linux (0 for no limit)")
+	rootCmd.Flags().Int("memory-limit", 0, "Memory in MB shared by all encoder processes, enforced with a cgroup v2 on Linux (0 for no limit)")
+
+	// Copy-through flags
+	rootCmd.Flags().Bool("copy-through", false, "Copy or remux already-efficient files instead of re-encoding them")
+	rootCmd.Flags().Float64("copy-through-min-gain", 20.0, "Minimum estimated size reduction (%) required to re-encode when copy-through is enabled")
+
+	// Chaptered recording flags
+	rootCmd.Flags().String("chapters", "join", "Camera recordings split into chapters (GoPro, DJI, AVCHD): join, separate or off")
+
+	// Segmented encoding flags
+	rootCmd.Flags().Bool("segments", true, "Encode long videos in resumable segments (timeout applies per segment)")
+	rootCmd.Flags().Int("segment-min-duration", 30, "Minimum video duration in minutes for segmented encoding")
+	rootCmd.Flags().Int("segment-seconds", 300, "Approximate segment length in seconds (cut at keyframes)")
+	rootCmd.Flags().Int("segment-parallel", 1, "Segments of one video encoded concurrently, using free worker slots")
+
 	// Organization flags
 	rootCmd.Flags().BoolP("organize-by-date", "o", true, "Organize files by date")
 	rootCmd.Flags().String("language", "en", "Language for month names (en, fr, es, de)")
@@ -114,22 +199,60 @@ func init() {
 	rootCmd.Flags().Int("timeout-photo", 300, "Timeout for photo conversion in seconds")
 	rootCmd.Flags().Int("timeout-video", 1800, "Timeout for video conversion in seconds")
 	rootCmd.Flags().Float64("min-output-ratio", 0.0, "Minimum output size ratio (0.0 uses format-specific defaults)")
+	rootCmd.Flags().Float64("min-savings", 0.0, "Minimum size reduction (%) an encode must achieve, otherwise the original is kept (negative disables)")
 
 	// Bind flags to viper
 	viper.BindPFlag("dry_run", rootCmd.Flags().Lookup("dry-run"))
 	viper.BindPFlag("keep_originals", rootCmd.Flags().Lookup("keep-originals"))
 	viper.BindPFlag("max_jobs", rootCmd.Flags().Lookup("jobs"))
+	viper.BindPFlag("dashboard", rootCmd.Flags().Lookup("tui"))
+	viper.BindPFlag("http_addr", rootCmd.Flags().Lookup("http"))
+	viper.BindPFlag("control_socket", rootCmd.Flags().Lookup("control-socket"))
+	viper.BindPFlag("schedule.run_windows", rootCmd.Flags().Lookup("run-window"))
+	viper.BindPFlag("schedule.outside", rootCmd.Flags().Lookup("outside-window"))
 	viper.BindPFlag("photo_format", rootCmd.Flags().Lookup("photo-format"))
 	viper.BindPFlag("photo_quality_avif", rootCmd.Flags().Lookup("photo-quality-avif"))
 	viper.BindPFlag("photo_quality_webp", rootCmd.Flags().Lookup("photo-quality-webp"))
 	viper.BindPFlag("video_codec", rootCmd.Flags().Lookup("video-codec"))
+	viper.BindPFlag("video_container", rootCmd.Flags().Lookup("video-container"))
+	viper.BindPFlag("video_mode", rootCmd.Flags().Lookup("video-mode"))
+	viper.BindPFlag("video_max_bitrate", rootCmd.Flags().Lookup("video-max-bitrate"))
+	viper.BindPFlag("video_target_bitrate", rootCmd.Flags().Lookup("video-target-bitrate"))
+	viper.BindPFlag("video_target_size_mb", rootCmd.Flags().Lookup("video-target-size"))
 	viper.BindPFlag("video_crf", rootCmd.Flags().Lookup("video-crf"))
 	viper.BindPFlag("video_acceleration", rootCmd.Flags().Lookup("video-acceleration"))
+	viper.BindPFlag("video_hw_backend", rootCmd.Flags().Lookup("video-hw-backend"))
+	viper.BindPFlag("av1.encoder", rootCmd.Flags().Lookup("av1-encoder"))
+	viper.BindPFlag("av1.speed", rootCmd.Flags().Lookup("av1-speed"))
+	viper.BindPFlag("av1.film_grain", rootCmd.Flags().Lookup("av1-film-grain"))
+	viper.BindPFlag("raw.backend", rootCmd.Flags().Lookup("raw-backend"))
+	viper.BindPFlag("raw.white_balance", rootCmd.Flags().Lookup("raw-white-balance"))
+	viper.BindPFlag("raw.exposure", rootCmd.Flags().Lookup("raw-exposure"))
+	viper.BindPFlag("raw.color_profile", rootCmd.Flags().Lookup("raw-color-profile"))
+	viper.BindPFlag("color.mode", rootCmd.Flags().Lookup("color-mode"))
+	viper.BindPFlag("color.target", rootCmd.Flags().Lookup("color-target"))
+	viper.BindPFlag("hdr_mode", rootCmd.Flags().Lookup("hdr"))
+	viper.BindPFlag("rotation_mode", rootCmd.Flags().Lookup("rotation"))
+	viper.BindPFlag("deinterlace.mode", rootCmd.Flags().Lookup("deinterlace"))
+	viper.BindPFlag("deinterlace.filter", rootCmd.Flags().Lookup("deinterlace-filter"))
+	viper.BindPFlag("deinterlace.rate", rootCmd.Flags().Lookup("deinterlace-rate"))
+	viper.BindPFlag("framerate.mode", rootCmd.Flags().Lookup("fps-mode"))
+	viper.BindPFlag("framerate.conform", rootCmd.Flags().Lookup("fps-conform"))
+	viper.BindPFlag("animated_mode", rootCmd.Flags().Lookup("animated"))
+	viper.BindPFlag("animated_video_codec", rootCmd.Flags().Lookup("animated-video-codec"))
 	viper.BindPFlag("organize_by_date", rootCmd.Flags().Lookup("organize-by-date"))
 	viper.BindPFlag("language", rootCmd.Flags().Lookup("language"))
 	viper.BindPFlag("timeout_photo", rootCmd.Flags().Lookup("timeout-photo"))
 	viper.BindPFlag("timeout_video", rootCmd.Flags().Lookup("timeout-video"))
 	viper.BindPFlag("min_output_size_ratio", rootCmd.Flags().Lookup("min-output-ratio"))
+	viper.BindPFlag("min_savings_percent", rootCmd.Flags().Lookup("min-savings"))
+	viper.BindPFlag("copy_through.enabled", rootCmd.Flags().Lookup("copy-through"))
+	viper.BindPFlag("copy_through.min_gain_percent", rootCmd.Flags().Lookup("copy-through-min-gain"))
+	viper.BindPFlag("chapters.mode", rootCmd.Flags().Lookup("chapters"))
+	viper.BindPFlag("segments.enabled", rootCmd.Flags().Lookup("segments"))
+	viper.BindPFlag("segments.min_duration_minutes", rootCmd.Flags().Lookup("segment-min-duration"))
+	viper.BindPFlag("segments.segment_seconds", rootCmd.Flags().Lookup("segment-seconds"))
+	viper.BindPFlag("segments.parallel", rootCmd.Flags().Lookup("segment-parallel"))
 	viper.BindPFlag("adaptive_workers.enabled", rootCmd.Flags().Lookup("adaptive-workers"))
 	viper.BindPFlag("adaptive_workers.min", rootCmd.Flags().Lookup("adaptive-workers-min"))
 	viper.BindPFlag("adaptive_workers.max", rootCmd.Flags().Lookup("adaptive-workers-max"))
@@ -137,6 +260,10 @@ func init() {
 	viper.BindPFlag("adaptive_workers.cpu_low", rootCmd.Flags().Lookup("adaptive-workers-cpu-low"))
 	viper.BindPFlag("adaptive_workers.mem_low_percent", rootCmd.Flags().Lookup("adaptive-workers-mem-low"))
 	viper.BindPFlag("adaptive_workers.interval_seconds", rootCmd.Flags().Lookup("adaptive-workers-interval"))
+	viper.BindPFlag("priority.nice", rootCmd.Flags().Lookup("nice"))
+	viper.BindPFlag("priority.io_class", rootCmd.Flags().Lookup("io-class"))
+	viper.BindPFlag("priority.cpu_limit", rootCmd.Flags().Lookup("cpu-limit"))
+	viper.BindPFlag("priority.memory_limit_mb", rootCmd.Flags().Lookup("memory-limit"))
 }
 
 func initConfig() {
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/kevindurb/media-converter/internal/utils"
 	"github.com/spf13/viper"
 )
 
@@ -17,6 +20,15 @@ type Config struct {
 	MaxJobs int
 	DryRun  bool
 
+	// Live terminal dashboard; plain log lines when stdout is not a terminal
+	Dashboard bool
+
+	// Address of the HTTP status API and web dashboard; empty disables it
+	HTTPAddr string
+
+	// Unix socket accepting queue commands; empty disables it
+	ControlSocket string
+
 	// Image settings
 	PhotoFormat      string
 	PhotoQualityAVIF int
@@ -26,6 +38,43 @@ type Config struct {
 	VideoCodec        string
 	VideoCRF          int
 	VideoAcceleration bool
+	VideoContainer    string
+
+	// Hardware encoder backend: auto, videotoolbox, nvenc, qsv or vaapi
+	VideoHWBackend string
+
+	// Rate control: "crf", "cq" (CRF capped at VideoMaxBitrate) or "2pass"
+	// (VideoTargetBitrate, or a bitrate derived from VideoTargetSizeMB)
+	VideoMode          string
+	VideoMaxBitrate    string
+	VideoTargetBitrate string
+	VideoTargetSizeMB  float64
+
+	// Software AV1 encoder selection
+	AV1 AV1Config
+
+	// RAW development
+	Raw RawConfig
+
+	// Colour management
+	Color ColorConfig
+
+	// HDR sources: "preserve" keeps 10-bit PQ/HLG, "tonemap" converts to SDR
+	HDRMode string
+
+	// Rotated sources: "bake" turns the pixels upright, "metadata" keeps the
+	// stored orientation and its display matrix
+	RotationMode string
+
+	// Interlaced sources
+	Deinterlace DeinterlaceConfig
+
+	// Frame timing of VFR and high-speed sources
+	FrameRate FrameRateConfig
+
+	// Animated images
+	AnimatedMode       string
+	AnimatedVideoCodec string
 
 	// Organization
 	OrganizeByDate bool
@@ -38,6 +87,7 @@ type Config struct {
 	MinOutputSizeRatio     float64
 	MinOutputSizeRatioAVIF float64
 	MinOutputSizeRatioWebP float64
+	MinSavingsPercent      float64
 
 	// Supported formats
 	PhotoFormats []string
@@ -45,6 +95,97 @@ type Config struct {
 
 	// Adaptive worker management
 	AdaptiveWorkers AdaptiveWorkerConfig
+
+	// Daily run windows gating the queue
+	Schedule ScheduleConfig
+
+	// Priority and resource limits of the encoder processes
+	Priority PriorityConfig
+
+	// Per-source routing rules, evaluated in order (first match wins)
+	Rules []RoutingRule
+
+	// Error decoding the rules table, reported by Validate
+	rulesErr error
+
+	// Copy-through for sources that would gain little from re-encoding
+	CopyThrough CopyThroughConfig
+
+	// Segmented encoding of long videos
+	Segments SegmentConfig
+
+	// Recordings split into chapters by the camera
+	Chapters ChapterConfig
+}
+
+type RawConfig struct {
+	Backend      string
+	WhiteBalance string
+	Exposure     float64
+	ColorProfile string
+}
+
+// AV1Config selects the AV1 encoder: libsvtav1, libaom-av1, librav1e or auto
+// (the first available, in that order). Speed is fast, medium or slow and is
+// mapped to each encoder's preset scale. FilmGrain (0-50) enables film-grain
+// synthesis, so noisy footage is not smoothed away.
+type AV1Config struct {
+	Encoder   string
+	Speed     string
+	FilmGrain int
+}
+
+// DeinterlaceConfig controls interlaced video handling. Mode "auto" trusts the
+// ffprobe field order and samples the video with idet only when it is
+// unknown, "idet" always samples (catching mis-flagged streams), "off"
+// encodes fields as-is. Rate "field" outputs one frame per field (50i becomes
+// 50p), "frame" one frame per frame pair (25p).
+type DeinterlaceConfig struct {
+	Mode   string
+	Filter string
+	Rate   string
+}
+
+// FrameRateConfig controls frame timing. Mode "auto" passes the source
+// timestamps through for variable-frame-rate and high-speed footage,
+// "passthrough" does it for every video, "conform" converts every video to a
+// constant Conform frames per second.
+type FrameRateConfig struct {
+	Mode    string
+	Conform float64
+}
+
+// ColorConfig controls ICC handling for photos. In "preserve" mode the source
+// profile is embedded as-is; "convert" transforms pixels to Target, which is
+// srgb, p3 or the path of an ICC file.
+type ColorConfig struct {
+	Mode   string
+	Target string
+}
+
+// SegmentConfig splits videos longer than MinDuration at keyframes into
+// segments of about SegmentDuration, encoded Parallel at a time and joined
+// without re-encoding. Finished segments are kept so interrupted runs resume.
+type SegmentConfig struct {
+	Enabled         bool
+	MinDuration     time.Duration
+	SegmentDuration time.Duration
+	Parallel        int
+}
+
+// ChapterConfig controls recordings split into several files by GoPro, DJI
+// and AVCHD cameras. Mode "join" concatenates them before a single encode,
+// "separate" converts each chapter under the group's name, "off" treats them
+// as unrelated videos.
+type ChapterConfig struct {
+	Mode string
+}
+
+type CopyThroughConfig struct {
+	Enabled        bool
+	MinGainPercent float64
+	Extensions     []string
+	Remux          bool
 }
 
 type AdaptiveWorkerConfig struct {
@@ -57,16 +198,87 @@ type AdaptiveWorkerConfig struct {
 	CheckInterval time.Duration
 }
 
+// Routing rule actions
+const (
+	ActionConvert = "convert"
+	ActionCopy    = "copy"
+	ActionSkip    = "skip"
+)
+
+// RoutingRule maps source characteristics to a conversion decision.
+// Empty target fields fall back to the global photo/video settings.
+type RoutingRule struct {
+	Name     string    `mapstructure:"name"`
+	Match    RuleMatch `mapstructure:"match"`
+	Action   string    `mapstructure:"action"`
+	Format   string    `mapstructure:"format"`
+	Codec    string    `mapstructure:"codec"`
+	Quality  int       `mapstructure:"quality"`
+	Lossless bool      `mapstructure:"lossless"`
+}
+
+// RuleMatch lists the conditions a source must satisfy for a rule to apply.
+// Zero values and nil pointers are ignored.
+type RuleMatch struct {
+	Extensions  []string `mapstructure:"extensions"`
+	PathGlob    string   `mapstructure:"path_glob"`
+	CameraModel string   `mapstructure:"camera_model"`
+	MinWidth    int      `mapstructure:"min_width"`
+	MaxWidth    int      `mapstructure:"max_width"`
+	MinHeight   int      `mapstructure:"min_height"`
+	MaxHeight   int      `mapstructure:"max_height"`
+	MinBitDepth int      `mapstructure:"min_bit_depth"`
+	MaxBitDepth int      `mapstructure:"max_bit_depth"`
+	Alpha       *bool    `mapstructure:"alpha"`
+	Animated    *bool    `mapstructure:"animated"`
+}
+
+// NeedsAttributes reports whether evaluating the match requires probing the file.
+func (m RuleMatch) NeedsAttributes() bool {
+	return m.CameraModel != "" ||
+		m.MinWidth > 0 || m.MaxWidth > 0 ||
+		m.MinHeight > 0 || m.MaxHeight > 0 ||
+		m.MinBitDepth > 0 || m.MaxBitDepth > 0 ||
+		m.Alpha != nil || m.Animated != nil
+}
+
 func NewConfig() *Config {
 	// Set default values for viper
 	viper.SetDefault("max_jobs", runtime.NumCPU()-2)
 	viper.SetDefault("dry_run", false)
+	viper.SetDefault("dashboard", false)
+	viper.SetDefault("http_addr", "")
+	viper.SetDefault("control_socket", "")
 	viper.SetDefault("photo_format", "avif")
 	viper.SetDefault("photo_quality_avif", 80)
 	viper.SetDefault("photo_quality_webp", 85)
 	viper.SetDefault("video_codec", "h265")
+	viper.SetDefault("video_container", "mp4")
+	viper.SetDefault("video_mode", "crf")
+	viper.SetDefault("video_max_bitrate", "")
+	viper.SetDefault("video_target_bitrate", "")
+	viper.SetDefault("video_target_size_mb", 0.0)
 	viper.SetDefault("video_crf", 28)
 	viper.SetDefault("video_acceleration", true)
+	viper.SetDefault("video_hw_backend", "auto")
+	viper.SetDefault("av1.encoder", "auto")
+	viper.SetDefault("av1.speed", "medium")
+	viper.SetDefault("av1.film_grain", 0)
+	viper.SetDefault("raw.backend", "magick")
+	viper.SetDefault("raw.white_balance", "camera")
+	viper.SetDefault("raw.exposure", 0.0)
+	viper.SetDefault("raw.color_profile", "srgb")
+	viper.SetDefault("color.mode", "preserve")
+	viper.SetDefault("color.target", "srgb")
+	viper.SetDefault("hdr_mode", "preserve")
+	viper.SetDefault("rotation_mode", "bake")
+	viper.SetDefault("deinterlace.mode", "auto")
+	viper.SetDefault("deinterlace.filter", "bwdif")
+	viper.SetDefault("deinterlace.rate", "field")
+	viper.SetDefault("framerate.mode", "auto")
+	viper.SetDefault("framerate.conform", 30.0)
+	viper.SetDefault("animated_mode", "image")
+	viper.SetDefault("animated_video_codec", "h265")
 	viper.SetDefault("organize_by_date", true)
 	viper.SetDefault("keep_originals", true)
 	viper.SetDefault("timeout_photo", 300)
@@ -74,6 +286,7 @@ func NewConfig() *Config {
 	viper.SetDefault("min_output_size_ratio", 0.005)
 	viper.SetDefault("min_output_size_ratio_avif", 0.001)
 	viper.SetDefault("min_output_size_ratio_webp", 0.003)
+	viper.SetDefault("min_savings_percent", 0.0)
 	viper.SetDefault("language", "en")
 	viper.SetDefault("adaptive_workers.enabled", false)
 	viper.SetDefault("adaptive_workers.min", 1)
@@ -82,16 +295,73 @@ func NewConfig() *Config {
 	viper.SetDefault("adaptive_workers.cpu_low", 50.0)
 	viper.SetDefault("adaptive_workers.mem_low_percent", 20.0)
 	viper.SetDefault("adaptive_workers.interval_seconds", 3)
+	viper.SetDefault("schedule.run_windows", []string{})
+	viper.SetDefault("schedule.outside", "finish")
+	viper.SetDefault("schedule.media", "all")
+	viper.SetDefault("priority.nice", 0)
+	viper.SetDefault("priority.io_class", "none")
+	viper.SetDefault("priority.io_level", 4)
+	viper.SetDefault("priority.cpu_limit", 0.0)
+	viper.SetDefault("priority.memory_limit_mb", 0)
+	viper.SetDefault("priority.cgroup", "/sys/fs/cgroup/media-converter")
+	viper.SetDefault("copy_through.enabled", false)
+	viper.SetDefault("copy_through.min_gain_percent", 20.0)
+	viper.SetDefault("copy_through.extensions", []string{})
+	viper.SetDefault("copy_through.remux", true)
+	viper.SetDefault("chapters.mode", "join")
+	viper.SetDefault("segments.enabled", true)
+	viper.SetDefault("segments.min_duration_minutes", 30)
+	viper.SetDefault("segments.segment_seconds", 300)
+	viper.SetDefault("segments.parallel", 1)
 
 	cfg := &Config{
-		MaxJobs:                viper.GetInt("max_jobs"),
-		DryRun:                 viper.GetBool("dry_run"),
-		PhotoFormat:            viper.GetString("photo_format"),
-		PhotoQualityAVIF:       viper.GetInt("photo_quality_avif"),
-		PhotoQualityWebP:       viper.GetInt("photo_quality_webp"),
-		VideoCodec:             viper.GetString("video_codec"),
-		VideoCRF:               viper.GetInt("video_crf"),
-		VideoAcceleration:      viper.GetBool("video_acceleration"),
+		MaxJobs:           viper.GetInt("max_jobs"),
+		DryRun:            viper.GetBool("dry_run"),
+		PhotoFormat:       viper.GetString("photo_format"),
+		PhotoQualityAVIF:  viper.GetInt("photo_quality_avif"),
+		PhotoQualityWebP:  viper.GetInt("photo_quality_webp"),
+		VideoCodec:        viper.GetString("video_codec"),
+		VideoCRF:          viper.GetInt("video_crf"),
+		VideoAcceleration: viper.GetBool("video_acceleration"),
+		VideoContainer:    strings.ToLower(strings.TrimSpace(viper.GetString("video_container"))),
+
+		VideoHWBackend: strings.ToLower(strings.TrimSpace(viper.GetString("video_hw_backend"))),
+
+		VideoMode:          strings.ToLower(strings.TrimSpace(viper.GetString("video_mode"))),
+		VideoMaxBitrate:    strings.TrimSpace(viper.GetString("video_max_bitrate")),
+		VideoTargetBitrate: strings.TrimSpace(viper.GetString("video_target_bitrate")),
+		VideoTargetSizeMB:  viper.GetFloat64("video_target_size_mb"),
+		AV1: AV1Config{
+			Encoder:   strings.ToLower(strings.TrimSpace(viper.GetString("av1.encoder"))),
+			Speed:     strings.ToLower(strings.TrimSpace(viper.GetString("av1.speed"))),
+			FilmGrain: viper.GetInt("av1.film_grain"),
+		},
+		Raw: RawConfig{
+			Backend:      strings.ToLower(strings.TrimSpace(viper.GetString("raw.backend"))),
+			WhiteBalance: strings.ToLower(strings.TrimSpace(viper.GetString("raw.white_balance"))),
+			Exposure:     viper.GetFloat64("raw.exposure"),
+			ColorProfile: strings.ToLower(strings.TrimSpace(viper.GetString("raw.color_profile"))),
+		},
+		Color: ColorConfig{
+			Mode:   strings.ToLower(strings.TrimSpace(viper.GetString("color.mode"))),
+			Target: strings.TrimSpace(viper.GetString("color.target")),
+		},
+		Deinterlace: DeinterlaceConfig{
+			Mode:   strings.ToLower(strings.TrimSpace(viper.GetString("deinterlace.mode"))),
+			Filter: strings.ToLower(strings.TrimSpace(viper.GetString("deinterlace.filter"))),
+			Rate:   strings.ToLower(strings.TrimSpace(viper.GetString("deinterlace.rate"))),
+		},
+		FrameRate: FrameRateConfig{
+			Mode:    strings.ToLower(strings.TrimSpace(viper.GetString("framerate.mode"))),
+			Conform: viper.GetFloat64("framerate.conform"),
+		},
+		Dashboard:              viper.GetBool("dashboard"),
+		HTTPAddr:               strings.TrimSpace(viper.GetString("http_addr")),
+		ControlSocket:          strings.TrimSpace(viper.GetString("control_socket")),
+		HDRMode:                strings.ToLower(strings.TrimSpace(viper.GetString("hdr_mode"))),
+		RotationMode:           strings.ToLower(strings.TrimSpace(viper.GetString("rotation_mode"))),
+		AnimatedMode:           strings.ToLower(strings.TrimSpace(viper.GetString("animated_mode"))),
+		AnimatedVideoCodec:     strings.ToLower(strings.TrimSpace(viper.GetString("animated_video_codec"))),
 		OrganizeByDate:         viper.GetBool("organize_by_date"),
 		KeepOriginals:          viper.GetBool("keep_originals"),
 		Language:               strings.ToLower(viper.GetString("language")),
@@ -100,6 +370,7 @@ func NewConfig() *Config {
 		MinOutputSizeRatio:     viper.GetFloat64("min_output_size_ratio"),
 		MinOutputSizeRatioAVIF: viper.GetFloat64("min_output_size_ratio_avif"),
 		MinOutputSizeRatioWebP: viper.GetFloat64("min_output_size_ratio_webp"),
+		MinSavingsPercent:      viper.GetFloat64("min_savings_percent"),
 		PhotoFormats: []string{
 			"jpg", "jpeg", "heic", "heif", "cr2", "arw", "nef", "dng",
 			"tiff", "tif", "png", "raw", "bmp", "gif", "webp",
@@ -117,6 +388,62 @@ func NewConfig() *Config {
 			MemLowPercent: viper.GetFloat64("adaptive_workers.mem_low_percent"),
 			CheckInterval: time.Duration(viper.GetInt("adaptive_workers.interval_seconds")) * time.Second,
 		},
+		Schedule: ScheduleConfig{
+			RunWindows: viper.GetStringSlice("schedule.run_windows"),
+			Weekdays:   viper.GetStringMapStringSlice("schedule.weekdays"),
+			Outside:    strings.ToLower(strings.TrimSpace(viper.GetString("schedule.outside"))),
+			Media:      strings.ToLower(strings.TrimSpace(viper.GetString("schedule.media"))),
+		},
+		Priority: PriorityConfig{
+			Nice:          viper.GetInt("priority.nice"),
+			IOClass:       strings.ToLower(strings.TrimSpace(viper.GetString("priority.io_class"))),
+			IOLevel:       viper.GetInt("priority.io_level"),
+			CPULimit:      viper.GetFloat64("priority.cpu_limit"),
+			MemoryLimitMB: viper.GetInt("priority.memory_limit_mb"),
+			Cgroup:        strings.TrimSpace(viper.GetString("priority.cgroup")),
+		},
+		CopyThrough: CopyThroughConfig{
+			Enabled:        viper.GetBool("copy_through.enabled"),
+			MinGainPercent: viper.GetFloat64("copy_through.min_gain_percent"),
+			Extensions:     viper.GetStringSlice("copy_through.extensions"),
+			Remux:          viper.GetBool("copy_through.remux"),
+		},
+		Chapters: ChapterConfig{
+			Mode: strings.ToLower(strings.TrimSpace(viper.GetString("chapters.mode"))),
+		},
+		Segments: SegmentConfig{
+			Enabled:         viper.GetBool("segments.enabled"),
+			MinDuration:     time.Duration(viper.GetInt("segments.min_duration_minutes")) * time.Minute,
+			SegmentDuration: time.Duration(viper.GetInt("segments.segment_seconds")) * time.Second,
+			Parallel:        viper.GetInt("segments.parallel"),
+		},
+	}
+
+	if cfg.CopyThrough.MinGainPercent < 0 {
+		cfg.CopyThrough.MinGainPercent = 0
+	}
+	for i, ext := range cfg.CopyThrough.Extensions {
+		cfg.CopyThrough.Extensions[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+	}
+
+	if err := viper.UnmarshalKey("rules", &cfg.Rules); err != nil {
+		cfg.Rules = nil
+		cfg.rulesErr = err
+	}
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		rule.Action = strings.ToLower(strings.TrimSpace(rule.Action))
+		if rule.Action == "" {
+			rule.Action = ActionConvert
+		}
+		rule.Format = strings.ToLower(strings.TrimSpace(rule.Format))
+		rule.Codec = strings.ToLower(strings.TrimSpace(rule.Codec))
+		for j, ext := range rule.Match.Extensions {
+			rule.Match.Extensions[j] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
 	}
 
 	// Validate max jobs
@@ -142,6 +469,13 @@ func NewConfig() *Config {
 		cfg.Language = "en"
 	}
 
+	if cfg.Segments.SegmentDuration < 10*time.Second {
+		cfg.Segments.SegmentDuration = 10 * time.Second
+	}
+	if cfg.Segments.Parallel < 1 {
+		cfg.Segments.Parallel = 1
+	}
+
 	// Sanitize adaptive worker settings
 	if cfg.AdaptiveWorkers.MinWorkers < 1 {
 		cfg.AdaptiveWorkers.MinWorkers = 1
@@ -171,3 +505,172 @@ func NewConfig() *Config {
 
 	return cfg
 }
+
+// Validate reports configuration errors that cannot be sanitised silently.
+func (c *Config) Validate() error {
+	if c.HTTPAddr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPAddr); err != nil {
+			return fmt.Errorf("invalid HTTP address %q (expected host:port or :port): %w", c.HTTPAddr, err)
+		}
+	}
+	switch c.Raw.Backend {
+	case "magick", "dcraw_emu", "darktable", "rawtherapee", "embedded":
+	default:
+		return fmt.Errorf("unknown RAW backend %q (expected magick, dcraw_emu, darktable, rawtherapee or embedded)", c.Raw.Backend)
+	}
+	switch c.Raw.WhiteBalance {
+	case "camera", "auto":
+	default:
+		return fmt.Errorf("unknown RAW white balance %q (expected camera or auto)", c.Raw.WhiteBalance)
+	}
+	switch c.Raw.ColorProfile {
+	case "srgb", "adobe", "prophoto", "p3":
+	default:
+		return fmt.Errorf("unknown RAW colour profile %q (expected srgb, adobe, prophoto or p3)", c.Raw.ColorProfile)
+	}
+
+	switch c.Color.Mode {
+	case "preserve", "convert":
+	default:
+		return fmt.Errorf("unknown colour mode %q (expected preserve or convert)", c.Color.Mode)
+	}
+
+	switch c.AnimatedVideoCodec {
+	case "h265", "hevc", "h.265", "av1":
+	default:
+		return fmt.Errorf("unsupported --animated-video-codec %q (expected h265 or av1)", c.AnimatedVideoCodec)
+	}
+
+	switch c.VideoContainer {
+	case "mp4", "mkv":
+	case "webm":
+		if codec := strings.ToLower(c.VideoCodec); codec != "av1" {
+			return fmt.Errorf("webm container requires the av1 video codec (got %s)", c.VideoCodec)
+		}
+	default:
+		return fmt.Errorf("unknown video container %q (expected mp4, mkv or webm)", c.VideoContainer)
+	}
+
+	switch c.VideoMode {
+	case "crf":
+	case "cq":
+		if _, err := utils.ParseBitrate(c.VideoMaxBitrate); err != nil {
+			return fmt.Errorf("cq video mode requires a valid max bitrate: %w", err)
+		}
+	case "2pass":
+		if c.VideoTargetSizeMB <= 0 {
+			if _, err := utils.ParseBitrate(c.VideoTargetBitrate); err != nil {
+				return fmt.Errorf("2pass video mode requires a target size or a valid target bitrate: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown video mode %q (expected crf, cq or 2pass)", c.VideoMode)
+	}
+
+	switch c.VideoHWBackend {
+	case "auto", "videotoolbox", "nvenc", "qsv", "vaapi":
+	default:
+		return fmt.Errorf("unknown hardware backend %q (expected auto, videotoolbox, nvenc, qsv or vaapi)", c.VideoHWBackend)
+	}
+
+	switch c.AV1.Encoder {
+	case "auto", "libsvtav1", "libaom-av1", "librav1e":
+	default:
+		return fmt.Errorf("unknown AV1 encoder %q (expected auto, libsvtav1, libaom-av1 or librav1e)", c.AV1.Encoder)
+	}
+	switch c.AV1.Speed {
+	case "fast", "medium", "slow":
+	default:
+		return fmt.Errorf("unknown AV1 speed %q (expected fast, medium or slow)", c.AV1.Speed)
+	}
+	if c.AV1.FilmGrain < 0 || c.AV1.FilmGrain > 50 {
+		return fmt.Errorf("AV1 film grain must be between 0 and 50")
+	}
+
+	switch c.HDRMode {
+	case "preserve", "tonemap":
+	default:
+		return fmt.Errorf("unknown HDR mode %q (expected preserve or tonemap)", c.HDRMode)
+	}
+
+	switch c.RotationMode {
+	case "bake", "metadata":
+	default:
+		return fmt.Errorf("unknown rotation mode %q (expected bake or metadata)", c.RotationMode)
+	}
+
+	switch c.Deinterlace.Mode {
+	case "auto", "idet", "off":
+	default:
+		return fmt.Errorf("unknown deinterlace mode %q (expected auto, idet or off)", c.Deinterlace.Mode)
+	}
+	switch c.Deinterlace.Filter {
+	case "bwdif", "yadif":
+	default:
+		return fmt.Errorf("unknown deinterlace filter %q (expected bwdif or yadif)", c.Deinterlace.Filter)
+	}
+	switch c.Deinterlace.Rate {
+	case "field", "frame":
+	default:
+		return fmt.Errorf("unknown deinterlace rate %q (expected field or frame)", c.Deinterlace.Rate)
+	}
+
+	switch c.FrameRate.Mode {
+	case "auto", "passthrough":
+	case "conform":
+		if c.FrameRate.Conform <= 0 || c.FrameRate.Conform > 240 {
+			return fmt.Errorf("conform frame rate must be between 0 and 240 fps")
+		}
+	default:
+		return fmt.Errorf("unknown frame rate mode %q (expected auto, passthrough or conform)", c.FrameRate.Mode)
+	}
+
+	switch c.Chapters.Mode {
+	case "join", "separate", "off":
+	default:
+		return fmt.Errorf("unknown chapters mode %q (expected join, separate or off)", c.Chapters.Mode)
+	}
+
+	switch c.AnimatedMode {
+	case "image", "video", "flatten":
+	default:
+		return fmt.Errorf("unknown animated mode %q (expected image, video or flatten)", c.AnimatedMode)
+	}
+
+	if err := c.Schedule.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Priority.validate(); err != nil {
+		return err
+	}
+
+	if c.rulesErr != nil {
+		return fmt.Errorf("invalid routing rules: %w", c.rulesErr)
+	}
+	for _, rule := range c.Rules {
+		switch rule.Action {
+		case ActionConvert, ActionCopy, ActionSkip:
+		default:
+			return fmt.Errorf("%s: unknown action %q (expected convert, copy or skip)", rule.Name, rule.Action)
+		}
+
+		switch rule.Format {
+		case "", "avif", "webp":
+		default:
+			return fmt.Errorf("%s: unsupported photo format %q (expected avif or webp)", rule.Name, rule.Format)
+		}
+
+		switch rule.Codec {
+		case "", "h265", "hevc", "h.265", "h264", "avc", "h.264", "av1":
+		default:
+			return fmt.Errorf("%s: unsupported video codec %q (expected h265, h264 or av1)", rule.Name, rule.Codec)
+		}
+
+		if rule.Quality < 0 || rule.Quality > 100 {
+			return fmt.Errorf("%s: quality must be between 0 and 100", rule.Name)
+		}
+	}
+
+	return nil
+}
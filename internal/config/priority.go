@@ -0,0 +1,53 @@
+package config
+
+import "fmt"
+
+// PriorityConfig lowers the priority of the encoder processes (ffmpeg,
+// magick, avifenc and the RAW developers) so the machine stays responsive.
+// Nice is the CPU niceness, IOClass the I/O scheduling class ("none" leaves
+// it unchanged, "idle", "best-effort" or "realtime") and IOLevel the level
+// within the class, 0 being the highest. CPULimit (cores) and MemoryLimitMB
+// are shared by all encoders through the cgroup v2 directory Cgroup; 0 means
+// no limit.
+type PriorityConfig struct {
+	Nice          int
+	IOClass       string
+	IOLevel       int
+	CPULimit      float64
+	MemoryLimitMB int
+	Cgroup        string
+}
+
+// Enabled reports whether any priority or limit is configured.
+func (p PriorityConfig) Enabled() bool {
+	return p.Nice != 0 || p.IOClass != "none" || p.Limited()
+}
+
+// Limited reports whether a CPU or memory limit is configured.
+func (p PriorityConfig) Limited() bool {
+	return p.CPULimit > 0 || p.MemoryLimitMB > 0
+}
+
+func (p PriorityConfig) validate() error {
+	if p.Nice < -20 || p.Nice > 19 {
+		return fmt.Errorf("nice must be between -20 and 19, got %d", p.Nice)
+	}
+	switch p.IOClass {
+	case "none", "idle", "best-effort", "realtime":
+	default:
+		return fmt.Errorf("unknown I/O class %q (expected none, idle, best-effort or realtime)", p.IOClass)
+	}
+	if p.IOLevel < 0 || p.IOLevel > 7 {
+		return fmt.Errorf("I/O level must be between 0 and 7, got %d", p.IOLevel)
+	}
+	if p.CPULimit < 0 {
+		return fmt.Errorf("CPU limit must not be negative, got %g", p.CPULimit)
+	}
+	if p.MemoryLimitMB < 0 {
+		return fmt.Errorf("memory limit must not be negative, got %d MB", p.MemoryLimitMB)
+	}
+	if p.Limited() && p.Cgroup == "" {
+		return fmt.Errorf("CPU and memory limits need a cgroup directory")
+	}
+	return nil
+}
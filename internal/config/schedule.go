@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleConfig restricts conversions to daily run windows such as
+// "22:00-07:00". A "@N" suffix sets the concurrency inside the window and
+// "@max" uses every core. Weekdays replaces the windows on the named days;
+// an empty list keeps the day closed. Outside the windows new files wait
+// and running ones "finish" or are "suspend"ed until the next window. Media
+// is "all" or "video", the phase the windows apply to.
+type ScheduleConfig struct {
+	RunWindows []string
+	Weekdays   map[string][]string
+	Outside    string
+	Media      string
+}
+
+// Enabled reports whether any run window is configured.
+func (s ScheduleConfig) Enabled() bool {
+	return len(s.RunWindows) > 0 || len(s.Weekdays) > 0
+}
+
+// RunWindowMax is the RunWindow limit using every worker.
+const RunWindowMax = -1
+
+// RunWindow is a daily time range. End is before Start for a window running
+// past midnight. Limit is the concurrency inside the window, 0 leaving it
+// unchanged.
+type RunWindow struct {
+	Start time.Duration
+	End   time.Duration
+	Limit int
+}
+
+// ParseRunWindow parses "HH:MM-HH:MM", optionally followed by "@N" or "@max".
+func ParseRunWindow(value string) (RunWindow, error) {
+	var window RunWindow
+	span, limit, hasLimit := strings.Cut(strings.TrimSpace(value), "@")
+	if hasLimit {
+		limit = strings.ToLower(strings.TrimSpace(limit))
+		if limit == "max" {
+			window.Limit = RunWindowMax
+		} else {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 {
+				return window, fmt.Errorf("run window %q: concurrency must be a positive number or max", value)
+			}
+			window.Limit = n
+		}
+	}
+
+	start, end, found := strings.Cut(span, "-")
+	if !found {
+		return window, fmt.Errorf("run window %q: expected HH:MM-HH:MM", value)
+	}
+	var err error
+	if window.Start, err = parseClock(start); err != nil {
+		return window, fmt.Errorf("run window %q: %w", value, err)
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return window, fmt.Errorf("run window %q: %w", value, err)
+	}
+	if window.Start == window.End || window.Start == 24*time.Hour {
+		return window, fmt.Errorf("run window %q is empty", value)
+	}
+	return window, nil
+}
+
+// parseClock parses a time of day from 00:00 to 24:00.
+func parseClock(value string) (time.Duration, error) {
+	hours, minutes, found := strings.Cut(strings.TrimSpace(value), ":")
+	h, herr := strconv.Atoi(hours)
+	m, merr := strconv.Atoi(minutes)
+	if !found || herr != nil || merr != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m > 0) {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// ParseWeekday accepts full and three-letter English day names.
+func ParseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func (s ScheduleConfig) validate() error {
+	switch s.Outside {
+	case "finish", "suspend":
+	default:
+		return fmt.Errorf("unknown schedule outside mode %q (expected finish or suspend)", s.Outside)
+	}
+	switch s.Media {
+	case "all", "video":
+	default:
+		return fmt.Errorf("unknown schedule media %q (expected all or video)", s.Media)
+	}
+
+	for _, value := range s.RunWindows {
+		if _, err := ParseRunWindow(value); err != nil {
+			return err
+		}
+	}
+	for day, windows := range s.Weekdays {
+		if _, ok := ParseWeekday(day); !ok {
+			return fmt.Errorf("unknown schedule weekday %q", day)
+		}
+		for _, value := range windows {
+			if _, err := ParseRunWindow(value); err != nil {
+				return fmt.Errorf("%s: %w", day, err)
+			}
+		}
+	}
+	return nil
+}
@@ -34,6 +34,17 @@ func (l *AdaptiveLimiter) Acquire() {
 	l.mu.Unlock()
 }
 
+// TryAcquire takes a worker slot when one is free, without waiting.
+func (l *AdaptiveLimiter) TryAcquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active >= l.limit {
+		return false
+	}
+	l.active++
+	return true
+}
+
 // Release frees a worker slot and wakes up any waiting goroutine.
 func (l *AdaptiveLimiter) Release() {
 	l.mu.Lock()
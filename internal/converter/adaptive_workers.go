@@ -25,6 +25,9 @@ type ResourceMonitor struct {
 	interval time.Duration
 	log      *logger.Logger
 
+	// Encoder limits the readings are relative to, nil for the whole system
+	budget *resourceBudget
+
 	warnMu    sync.Mutex
 	warnedCPU bool
 	warnedMem bool
@@ -69,6 +72,10 @@ func (m *ResourceMonitor) Start(ctx context.Context) <-chan ResourceSnapshot {
 					m.warnOnceMem(err)
 				}
 
+				if m.budget != nil {
+					snap = m.budget.apply(snap)
+				}
+
 				select {
 				case out <- snap:
 				default:
@@ -0,0 +1,41 @@
+package converter
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// animatableFormats lists photo extensions that may hold several frames.
+var animatableFormats = []string{"gif", "webp", "png"}
+
+// detectAnimation counts frames of GIF/WebP/APNG sources and applies the
+// configured animated mode so that animations are never flattened silently.
+func (c *Converter) detectAnimation(inputPath string, plan *conversionPlan) {
+	info, err := utils.GetAnimationInfo(inputPath)
+	if err != nil || !info.Animated() {
+		return
+	}
+	plan.Animation = &info
+
+	// Explicit routing rules take precedence over the global mode
+	if plan.MediaType != "photo" || plan.RuleName != "" {
+		return
+	}
+
+	filename := filepath.Base(inputPath)
+	switch c.config.AnimatedMode {
+	case "video":
+		if utils.HasExtension(inputPath, []string{"webp"}) {
+			c.logger.Warn(fmt.Sprintf("🎞️  %s: ffmpeg cannot decode animated WebP, keeping it as an animated image", filename))
+			return
+		}
+		plan.MediaType = "video"
+		plan.Codec = c.config.AnimatedVideoCodec
+		plan.Quality = c.config.VideoCRF
+	case "flatten":
+		c.logger.Warn(fmt.Sprintf("🎞️  %s has %d frames; only the first frame will be kept", filename, info.Frames))
+		plan.Flatten = true
+	}
+}
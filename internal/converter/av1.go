@@ -0,0 +1,137 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// av1Encoders lists the software AV1 encoders in order of preference: SVT-AV1
+// is several times faster than libaom on CPU-only machines.
+var av1Encoders = []string{"libsvtav1", "libaom-av1", "librav1e"}
+
+// av1SpeedPresets maps the fast/medium/slow speed setting to each encoder's
+// preset scale (SVT -preset, libaom -cpu-used, rav1e -speed).
+var av1SpeedPresets = map[string]map[string]int{
+	"libsvtav1":  {"fast": 10, "medium": 8, "slow": 5},
+	"libaom-av1": {"fast": 6, "medium": 4, "slow": 2},
+	"librav1e":   {"fast": 9, "medium": 6, "slow": 4},
+}
+
+// CRF range accepted for AV1 outputs, on the 0-63 scale
+const (
+	av1MinCRF = 28
+	av1MaxCRF = 45
+)
+
+// availableEncoders returns the encoders compiled into ffmpeg, or nil when
+// they cannot be listed.
+func (c *Converter) availableEncoders() map[string]bool {
+	c.encodersOnce.Do(func() {
+		encoders, err := utils.ListFFmpegEncoders(c.ffmpegCommand)
+		if err != nil {
+			c.logger.Warn(fmt.Sprintf("Unable to detect ffmpeg encoders: %v", err))
+			return
+		}
+		c.encoders = encoders
+	})
+	return c.encoders
+}
+
+// chooseAV1Encoder picks the configured AV1 encoder, or the first available
+// one when it is missing from ffmpeg or is "auto". SVT-AV1 cannot run two-pass
+// encodes through ffmpeg. A nil available map means encoders are unknown and
+// the preferred encoder is trusted. The returned note explains a fallback.
+func chooseAV1Encoder(preferred string, available map[string]bool, twoPass bool) (string, string, error) {
+	if preferred == "" {
+		preferred = "auto"
+	}
+	candidates := av1Encoders
+	if preferred != "auto" {
+		candidates = append([]string{preferred}, av1Encoders...)
+	}
+
+	for _, encoder := range candidates {
+		switch {
+		case twoPass && encoder == "libsvtav1":
+			continue
+		case available != nil && !available[encoder]:
+			continue
+		}
+		note := ""
+		if preferred != "auto" && encoder != preferred {
+			reason := "is not available in ffmpeg"
+			if twoPass && preferred == "libsvtav1" {
+				reason = "does not support two-pass encoding"
+			}
+			note = fmt.Sprintf("%s %s, using %s", preferred, reason, encoder)
+		}
+		return encoder, note, nil
+	}
+	return "", "", fmt.Errorf("no AV1 encoder available in ffmpeg (tried %v)", av1Encoders)
+}
+
+// av1EncoderArgs returns the speed preset and film-grain arguments of an AV1
+// encoder. rav1e has no film-grain synthesis through ffmpeg.
+func av1EncoderArgs(encoder, speed string, filmGrain int) []string {
+	preset := strconv.Itoa(av1SpeedPresets[encoder][speed])
+
+	switch encoder {
+	case "libsvtav1":
+		args := []string{"-preset", preset}
+		if filmGrain > 0 {
+			// Keep the source grain in the picture and add synthesized grain on top
+			args = append(args, "-svtav1-params", fmt.Sprintf("film-grain=%d:film-grain-denoise=0", filmGrain))
+		}
+		return args
+	case "librav1e":
+		return []string{"-speed", preset}
+	default:
+		args := []string{"-cpu-used", preset, "-row-mt", "1"}
+		if filmGrain > 0 {
+			args = append(args, "-denoise-noise-level", strconv.Itoa(filmGrain))
+		}
+		return args
+	}
+}
+
+// appendSVTAV1Params adds parameters to the -svtav1-params already in the
+// encoder arguments, since ffmpeg only keeps the last occurrence.
+func appendSVTAV1Params(args, params []string) []string {
+	if len(params) == 0 {
+		return args
+	}
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-svtav1-params" {
+			merged := append([]string{}, args...)
+			merged[i+1] += ":" + strings.Join(params, ":")
+			return merged
+		}
+	}
+	return append(args, "-svtav1-params", strings.Join(params, ":"))
+}
+
+// rav1eQuantizer converts a 0-63 CRF to rav1e's 0-255 quantizer scale.
+func rav1eQuantizer(crf int) int {
+	return clampInt((crf*255+31)/63, 0, 255)
+}
+
+// clampCRF limits a CRF to the range used for an encoder, reporting a changed
+// value once per encoder and value rather than overriding it silently.
+func (c *Converter) clampCRF(encoder string, value, minVal, maxVal int) int {
+	clamped := clampInt(value, minVal, maxVal)
+	if clamped != value {
+		c.warnOnce(fmt.Sprintf("crf:%s:%d", encoder, value),
+			fmt.Sprintf("🎚️  CRF %d is outside the %d-%d range used for %s, using %d", value, minVal, maxVal, encoder, clamped))
+	}
+	return clamped
+}
+
+// warnOnce logs a configuration warning the first time its key is seen.
+func (c *Converter) warnOnce(key, message string) {
+	if _, seen := c.warned.LoadOrStore(key, true); !seen {
+		c.logger.Warn(message)
+	}
+}
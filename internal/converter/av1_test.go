@@ -0,0 +1,59 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+const encodersOutput = `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libaom-av1           libaom AV1 (codec av1)
+ V....D librav1e             librav1e AV1 (codec av1)
+ A....D aac                  AAC (Advanced Audio Coding)
+`
+
+func TestChooseAV1EncoderFallsBack(t *testing.T) {
+	available := utils.ParseFFmpegEncoders(encodersOutput)
+	if !available["libaom-av1"] || available["libsvtav1"] || available["Video"] {
+		t.Fatalf("unexpected encoder list: %v", available)
+	}
+
+	encoder, note, err := chooseAV1Encoder("libsvtav1", available, false)
+	if err != nil || encoder != "libaom-av1" || !strings.Contains(note, "not available") {
+		t.Fatalf("expected libaom-av1 fallback, got %q %q %v", encoder, note, err)
+	}
+
+	// SVT-AV1 is skipped for two-pass encodes even when compiled in
+	available["libsvtav1"] = true
+	encoder, note, _ = chooseAV1Encoder("libsvtav1", available, true)
+	if encoder != "libaom-av1" || !strings.Contains(note, "two-pass") {
+		t.Fatalf("expected two-pass fallback, got %q %q", encoder, note)
+	}
+	if encoder, note, _ = chooseAV1Encoder("auto", available, false); encoder != "libsvtav1" || note != "" {
+		t.Fatalf("auto should pick libsvtav1 silently, got %q %q", encoder, note)
+	}
+
+	if _, _, err := chooseAV1Encoder("auto", map[string]bool{"libx264": true}, false); err == nil {
+		t.Fatal("expected an error without any AV1 encoder")
+	}
+}
+
+func TestAV1EncoderArgsMapSpeedAndFilmGrain(t *testing.T) {
+	cases := map[string]string{
+		"libsvtav1":  "-preset 8 -svtav1-params film-grain=8:film-grain-denoise=0",
+		"libaom-av1": "-cpu-used 4 -row-mt 1 -denoise-noise-level 8",
+		"librav1e":   "-speed 6",
+	}
+	for encoder, want := range cases {
+		if got := strings.Join(av1EncoderArgs(encoder, "medium", 8), " "); got != want {
+			t.Errorf("%s: got %q, want %q", encoder, got, want)
+		}
+	}
+	if q := rav1eQuantizer(63); q != 255 {
+		t.Errorf("CRF 63 should map to quantizer 255, got %d", q)
+	}
+}
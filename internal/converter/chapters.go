@@ -0,0 +1,319 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/config"
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// Largest gap between the end of a chapter and the start of the next one
+// for timestamp-based grouping (creation times have a one second resolution).
+const chapterGapTolerance = 3 * time.Second
+
+var (
+	// GoPro HERO6+: GH/GX/GL + chapter (01..) + file number
+	goproChapterRegex = regexp.MustCompile(`(?i)^G([HXL])(\d{2})(\d{4})\.MP4$`)
+	// GoPro HERO5 and older: GOPR + file number, then GP + chapter + file number
+	goproLegacyFirstRegex   = regexp.MustCompile(`(?i)^GOPR(\d{4})\.MP4$`)
+	goproLegacyChapterRegex = regexp.MustCompile(`(?i)^GP(\d{2})(\d{4})\.MP4$`)
+	// DJI: DJI_0001.MP4 or DJI_20230815143012_0001_D.MP4
+	djiRegex = regexp.MustCompile(`(?i)^DJI_(?:\d{14}_)?(\d{4})(?:_[A-Z])?\.MP4$`)
+	// AVCHD: BDMV/STREAM/00001.MTS
+	avchdRegex = regexp.MustCompile(`(?i)^(\d{5})\.MTS$`)
+)
+
+// chapterGroup is one recording a camera split into several files.
+type chapterGroup struct {
+	Name     string // output name shared by the chapters
+	Vendor   string
+	Chapters []string
+}
+
+// chapterJob is the chapter-group role of a queued video: the whole group
+// when Part is 0 (join mode), otherwise one chapter (separate mode).
+type chapterJob struct {
+	Group *chapterGroup
+	Part  int
+}
+
+// OutputName returns the base name of the converted file.
+func (j chapterJob) OutputName() string {
+	if j.Part == 0 {
+		return j.Group.Name
+	}
+	return fmt.Sprintf("%s_part%02d", j.Group.Name, j.Part)
+}
+
+// Label returns the name used in logs.
+func (j chapterJob) Label() string {
+	if j.Part == 0 {
+		return fmt.Sprintf("%s (%d chapters)", j.Group.Name, len(j.Group.Chapters))
+	}
+	return fmt.Sprintf("%s (chapter %d/%d)", filepath.Base(j.Group.Chapters[j.Part-1]), j.Part, len(j.Group.Chapters))
+}
+
+// clipTiming is the recording span of a file, used to confirm that numbered
+// files continue each other.
+type clipTiming struct {
+	Start    time.Time
+	Duration time.Duration
+}
+
+// chapterCandidate is a file matched by a vendor naming rule.
+type chapterCandidate struct {
+	path    string
+	key     string // files of one recording share the key
+	order   int
+	ordered bool // the order comes from the name; otherwise timestamps decide
+}
+
+// matchChapterName applies the vendor naming rules to a file.
+func matchChapterName(path string) (chapterCandidate, string, bool) {
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+
+	if m := goproChapterRegex.FindStringSubmatch(base); m != nil {
+		chapter, _ := strconv.Atoi(m[2])
+		key := fmt.Sprintf("%s|G%s%s", dir, strings.ToUpper(m[1]), m[3])
+		return chapterCandidate{path: path, key: key, order: chapter, ordered: true}, "GoPro", true
+	}
+	if m := goproLegacyFirstRegex.FindStringSubmatch(base); m != nil {
+		return chapterCandidate{path: path, key: dir + "|GOPR" + m[1], order: 0, ordered: true}, "GoPro", true
+	}
+	if m := goproLegacyChapterRegex.FindStringSubmatch(base); m != nil {
+		chapter, _ := strconv.Atoi(m[1])
+		return chapterCandidate{path: path, key: dir + "|GOPR" + m[2], order: chapter, ordered: true}, "GoPro", true
+	}
+	if m := djiRegex.FindStringSubmatch(base); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		return chapterCandidate{path: path, key: dir + "|DJI", order: number}, "DJI", true
+	}
+	if m := avchdRegex.FindStringSubmatch(base); m != nil && strings.EqualFold(filepath.Base(dir), "STREAM") {
+		number, _ := strconv.Atoi(m[1])
+		return chapterCandidate{path: path, key: dir + "|AVCHD", order: number}, "AVCHD", true
+	}
+	return chapterCandidate{}, "", false
+}
+
+// detectChapterGroups finds recordings split into several files. GoPro names
+// identify chapters directly; DJI and AVCHD files are numbered sequentially
+// per card, so consecutive files form a group only when each one starts where
+// the previous one ended. Files outside any group are not returned.
+func detectChapterGroups(files []string, timing func(string) (clipTiming, bool)) []chapterGroup {
+	candidates := make(map[string][]chapterCandidate)
+	vendors := make(map[string]string)
+	var keys []string
+	for _, path := range files {
+		candidate, vendor, ok := matchChapterName(path)
+		if !ok {
+			continue
+		}
+		if _, seen := candidates[candidate.key]; !seen {
+			keys = append(keys, candidate.key)
+		}
+		candidates[candidate.key] = append(candidates[candidate.key], candidate)
+		vendors[candidate.key] = vendor
+	}
+
+	var groups []chapterGroup
+	for _, key := range keys {
+		list := candidates[key]
+		sort.Slice(list, func(i, j int) bool { return list[i].order < list[j].order })
+
+		if list[0].ordered {
+			if len(list) > 1 {
+				groups = append(groups, newChapterGroup(vendors[key], list))
+			}
+			continue
+		}
+
+		// Sequential numbering: split where the recording is not continuous
+		start := 0
+		for i := 1; i <= len(list); i++ {
+			if i < len(list) && list[i].order == list[i-1].order+1 && continues(list[i-1].path, list[i].path, timing) {
+				continue
+			}
+			if i-start > 1 {
+				groups = append(groups, newChapterGroup(vendors[key], list[start:i]))
+			}
+			start = i
+		}
+	}
+	return groups
+}
+
+// continues reports whether next starts where previous ended.
+func continues(previous, next string, timing func(string) (clipTiming, bool)) bool {
+	prev, ok := timing(previous)
+	if !ok {
+		return false
+	}
+	following, ok := timing(next)
+	if !ok {
+		return false
+	}
+	gap := following.Start.Sub(prev.Start.Add(prev.Duration))
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap <= chapterGapTolerance
+}
+
+func newChapterGroup(vendor string, list []chapterCandidate) chapterGroup {
+	group := chapterGroup{Vendor: vendor}
+	for _, candidate := range list {
+		group.Chapters = append(group.Chapters, candidate.path)
+	}
+	first := filepath.Base(list[0].path)
+	group.Name = strings.TrimSuffix(first, filepath.Ext(first))
+	return group
+}
+
+// probeClipTiming reads when a file started recording: its creation_time tag,
+// or its modification time (written when recording stopped) minus its duration.
+func probeClipTiming(path string) (clipTiming, bool) {
+	probe, err := utils.ProbeMedia(path)
+	if err != nil {
+		return clipTiming{}, false
+	}
+	duration := time.Duration(probe.DurationSeconds() * float64(time.Second))
+	if duration <= 0 {
+		return clipTiming{}, false
+	}
+	if created, err := time.Parse(time.RFC3339Nano, probe.Tag("creation_time")); err == nil {
+		return clipTiming{Start: created, Duration: duration}, true
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return clipTiming{}, false
+	}
+	return clipTiming{Start: info.ModTime().Add(-duration), Duration: duration}, true
+}
+
+// planChapterJobs detects chapter groups among the videos and returns the
+// queue. In join mode a group is queued once, under its first chapter; in
+// separate mode every chapter stays queued and is named after its group.
+func (c *Converter) planChapterJobs(videoFiles []string) []string {
+	c.chapters = make(map[string]chapterJob)
+	if c.config.Chapters.Mode == "off" {
+		return videoFiles
+	}
+
+	groups := detectChapterGroups(videoFiles, probeClipTiming)
+	if len(groups) == 0 {
+		return videoFiles
+	}
+
+	grouped := make(map[string]bool)
+	for i := range groups {
+		group := &groups[i]
+		c.logger.Info(fmt.Sprintf("🧩 %s recording split into %d chapters: %s", group.Vendor, len(group.Chapters), group.Name))
+		for part, path := range group.Chapters {
+			if c.config.Chapters.Mode == "separate" {
+				c.chapters[path] = chapterJob{Group: group, Part: part + 1}
+				continue
+			}
+			grouped[path] = true
+		}
+		if c.config.Chapters.Mode != "separate" {
+			c.chapters[group.Chapters[0]] = chapterJob{Group: group}
+		}
+	}
+
+	queue := make([]string, 0, len(videoFiles))
+	for _, path := range videoFiles {
+		if _, head := c.chapters[path]; grouped[path] && !head {
+			continue // queued with its group
+		}
+		queue = append(queue, path)
+	}
+	return queue
+}
+
+// convertChapterGroup converts a joined chapter group. Rules are evaluated on
+// the first chapter; copy-through is not applied, joining is the point.
+func (c *Converter) convertChapterGroup(job chapterJob, fileType string) error {
+	first := job.Group.Chapters[0]
+	plan := c.planConversion(first, fileType)
+
+	switch plan.Action {
+	case config.ActionSkip:
+		c.logger.Info(fmt.Sprintf("⏭️  %s skipped (%s)", job.Label(), plan.RuleName))
+		c.stats.mu.Lock()
+		c.stats.ruleSkippedFiles++
+		c.stats.mu.Unlock()
+		c.metrics.fileEvent(eventSkipped, fileType)
+		return nil
+	case config.ActionCopy:
+		for _, chapter := range job.Group.Chapters {
+			if err := c.copyOriginal(chapter, fileType, plan.RuleName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	plan.Chapters = &job
+	return c.convertVideo(first, plan)
+}
+
+// joinChapters concatenates the chapters without re-encoding into a Matroska
+// file. Video, audio and subtitles are kept; camera telemetry tracks cannot
+// be concatenated and are dropped.
+func (c *Converter) joinChapters(queued string, group *chapterGroup, listPath, joinedPath string) error {
+	var list strings.Builder
+	for _, chapter := range group.Chapters {
+		absolute, err := filepath.Abs(chapter)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(absolute, "'", `'\''`))
+	}
+	if err := os.WriteFile(listPath, []byte(list.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write chapter list: %w", err)
+	}
+	defer os.Remove(listPath)
+
+	ctx, cancel := c.encodeContext(context.Background(), queued, c.config.ConversionTimeoutVideo)
+	defer cancel()
+
+	cmd := c.newFFmpegCommand(ctx,
+		"-f", "concat", "-safe", "0", "-i", listPath,
+		"-map", "0:v:0", "-map", "0:a?", "-map", "0:s?",
+		"-c", "copy",
+		"-map_metadata", "0",
+		"-f", "matroska",
+		"-y", joinedPath,
+	)
+	if output, err := c.processOutput(queued, cmd); err != nil {
+		return fmt.Errorf("failed to join chapters: %w - FFmpeg Error: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// keepChapterOriginals discards a joined encode that did not save enough
+// space and copies every chapter into the organised tree instead.
+func (c *Converter) keepChapterOriginals(group *chapterGroup, encodedPath string, savings float64) error {
+	os.Remove(encodedPath)
+
+	c.stats.mu.Lock()
+	c.stats.keptOriginals = append(c.stats.keptOriginals, keptOriginal{name: group.Name, savings: savings})
+	c.stats.mu.Unlock()
+
+	reason := fmt.Sprintf("encode saved %.1f%%, minimum %.1f%%", savings, c.config.MinSavingsPercent)
+	for _, chapter := range group.Chapters {
+		if err := c.placeOriginal(chapter, "video", reason, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package converter
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetectChapterGroups(t *testing.T) {
+	card := filepath.Join("card", "DCIM")
+	stream := filepath.Join("card", "PRIVATE", "AVCHD", "BDMV", "STREAM")
+	start := time.Date(2024, 7, 14, 10, 0, 0, 0, time.UTC)
+	timings := map[string]clipTiming{
+		// 00001-00002 are one recording split at 2 GB, 00003 starts later
+		filepath.Join(stream, "00001.MTS"): {Start: start, Duration: 20 * time.Minute},
+		filepath.Join(stream, "00002.MTS"): {Start: start.Add(20*time.Minute + time.Second), Duration: 5 * time.Minute},
+		filepath.Join(stream, "00003.MTS"): {Start: start.Add(time.Hour), Duration: time.Minute},
+	}
+	timing := func(path string) (clipTiming, bool) {
+		info, ok := timings[path]
+		return info, ok
+	}
+
+	files := []string{
+		filepath.Join(card, "GX020123.MP4"),
+		filepath.Join(card, "GX010123.MP4"),
+		filepath.Join(card, "GX010124.MP4"), // single chapter
+		filepath.Join(card, "GOPR0042.MP4"),
+		filepath.Join(card, "GP010042.MP4"),
+		filepath.Join(card, "IMG_0001.MOV"),
+		filepath.Join(stream, "00001.MTS"),
+		filepath.Join(stream, "00002.MTS"),
+		filepath.Join(stream, "00003.MTS"),
+	}
+
+	groups := detectChapterGroups(files, timing)
+	want := []chapterGroup{
+		{Name: "GX010123", Vendor: "GoPro", Chapters: []string{files[1], files[0]}},
+		{Name: "GOPR0042", Vendor: "GoPro", Chapters: []string{files[3], files[4]}},
+		{Name: "00001", Vendor: "AVCHD", Chapters: []string{files[6], files[7]}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("unexpected groups:\n got %+v\nwant %+v", groups, want)
+	}
+
+	part := chapterJob{Group: &groups[0], Part: 2}
+	if part.OutputName() != "GX010123_part02" {
+		t.Errorf("unexpected separate chapter name: %s", part.OutputName())
+	}
+}
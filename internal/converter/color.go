@@ -0,0 +1,136 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// colorSettings holds the ICC profiles and tools resolved at startup.
+type colorSettings struct {
+	targetPath        string
+	targetDescription string
+	srgbPath          string
+	avifenc           bool
+}
+
+// checkColorManagement resolves the configured target profile and looks for
+// avifenc, which is needed to write nclx colour tags into AVIF files.
+func (c *Converter) checkColorManagement() error {
+	if _, err := exec.LookPath("avifenc"); err == nil {
+		c.color.avifenc = true
+	} else {
+		c.logger.Info("avifenc not found: AVIF nclx colour tags use ImageMagick defaults")
+	}
+
+	if srgb, err := utils.FindICCProfile("srgb"); err == nil {
+		c.color.srgbPath = srgb
+	}
+
+	if c.config.Color.Mode != "convert" {
+		return nil
+	}
+
+	target, err := utils.FindICCProfile(c.config.Color.Target)
+	if err != nil {
+		return err
+	}
+	description, err := utils.ReadICCDescription(target)
+	if err != nil {
+		return fmt.Errorf("invalid ICC profile %s: %w", target, err)
+	}
+	c.color.targetPath = target
+	c.color.targetDescription = description
+
+	if !isSRGBProfile(description) && c.color.srgbPath == "" {
+		c.logger.Warn("No sRGB ICC profile found: photos without an embedded profile will not be converted")
+	}
+
+	c.logger.Info(fmt.Sprintf("Colour management: converting photos to %s", description))
+	return nil
+}
+
+// colorArgs returns the ImageMagick operators for the configured colour
+// handling and the profile description the output is expected to carry.
+// An empty description means the output is untagged (assumed sRGB).
+func (c *Converter) colorArgs(sourcePath string) ([]string, string) {
+	source, err := utils.GetImageColorProfile(sourcePath)
+	if err != nil {
+		source = ""
+	}
+
+	if c.config.Color.Mode != "convert" {
+		// ImageMagick keeps embedded profiles unless stripped
+		return nil, source
+	}
+
+	transform := []string{"-intent", "Perceptual", "-black-point-compensation", "-profile", c.color.targetPath}
+	switch {
+	case source != "":
+		return transform, c.color.targetDescription
+	case isSRGBProfile(c.color.targetDescription):
+		// Untagged sources are sRGB, so the target profile is only assigned
+		return []string{"-profile", c.color.targetPath}, c.color.targetDescription
+	case c.color.srgbPath != "":
+		return append([]string{"-profile", c.color.srgbPath}, transform...), c.color.targetDescription
+	default:
+		return nil, ""
+	}
+}
+
+// cicpFor maps a profile description to the nclx colour primaries, transfer
+// characteristics and matrix coefficients written by avifenc. Profiles
+// without a CICP equivalent are marked unspecified and rely on the ICC.
+func cicpFor(description string, lossless bool) string {
+	matrix := "6"
+	if lossless {
+		matrix = "0" // Lossless AVIF requires the identity matrix
+	}
+
+	desc := strings.ToLower(description)
+	switch {
+	case desc == "" || isSRGBProfile(description):
+		return "1/13/" + matrix
+	case strings.Contains(desc, "p3") && !strings.Contains(desc, "dci"):
+		return "12/13/" + matrix
+	default:
+		return "2/2/" + matrix
+	}
+}
+
+func isSRGBProfile(description string) bool {
+	return strings.Contains(strings.ToLower(description), "srgb")
+}
+
+// encodeAVIFWithCICP encodes an intermediate PNG with avifenc so the output
+// carries the given nclx colour tags. A zero depth means 8 bits.
+func (c *Converter) encodeAVIFWithCICP(ctx context.Context, inputPath, intermediatePath, tempPath string, plan conversionPlan, cicp string, depth int) error {
+	args := []string{
+		"-q", fmt.Sprintf("%d", plan.Quality),
+		"--speed", "6",
+		"--cicp", cicp,
+	}
+	if depth == 0 {
+		depth = 8 // avifenc would otherwise follow the 16-bit intermediate
+	}
+	args = append(args, "--depth", fmt.Sprintf("%d", depth))
+	if plan.Lossless {
+		args = append(args, "--lossless")
+	}
+	args = append(args, intermediatePath, tempPath)
+
+	cmd := exec.CommandContext(ctx, "avifenc", args...)
+	var outputBuf strings.Builder
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = &outputBuf
+	if err := c.runProcess(inputPath, cmd); err != nil {
+		if output := strings.TrimSpace(outputBuf.String()); output != "" {
+			return fmt.Errorf("avifenc failed: %w - %s", err, output)
+		}
+		return fmt.Errorf("avifenc failed: %w", err)
+	}
+	return nil
+}
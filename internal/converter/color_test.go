@@ -0,0 +1,26 @@
+package converter
+
+import "testing"
+
+func TestCICPFor(t *testing.T) {
+	tests := []struct {
+		description string
+		lossless    bool
+		want        string
+	}{
+		{"sRGB IEC61966-2.1", false, "1/13/6"},
+		{"Display P3", false, "12/13/6"},
+		{"DCI-P3", false, "2/2/6"},
+		{"DCI(P3) RGB", false, "2/2/6"},
+		{"Adobe RGB (1998)", false, "2/2/6"},
+		{"", false, "1/13/6"},
+		{"sRGB IEC61966-2.1", true, "1/13/0"},
+		{"Display P3", true, "12/13/0"},
+		{"Adobe RGB (1998)", true, "2/2/0"},
+	}
+	for _, tt := range tests {
+		if got := cicpFor(tt.description, tt.lossless); got != tt.want {
+			t.Errorf("cicpFor(%q, %v) = %s, want %s", tt.description, tt.lossless, got, tt.want)
+		}
+	}
+}
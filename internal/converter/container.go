@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// videoContainer describes how an output container is muxed and which audio
+// and subtitle codecs it accepts.
+type videoContainer struct {
+	Name      string
+	Extension string
+	Muxer     string
+	MuxerArgs []string
+
+	// Audio tracks in CopyAudio are stored as-is, others are encoded to AudioCodec
+	AudioCodec             string
+	AudioBitratePerChannel int
+	CopyAudio              map[string]bool
+
+	// Text subtitles are converted to SubtitleCodec; bitmap subtitles are
+	// copied when KeepBitmapSubtitles is set and dropped otherwise
+	SubtitleCodec       string
+	KeepBitmapSubtitles bool
+
+	// SupportsTimecode enables the QuickTime timecode track
+	SupportsTimecode bool
+	// SupportsCodecTag allows overriding the sample entry tag (hvc1)
+	SupportsCodecTag bool
+}
+
+var videoContainers = map[string]videoContainer{
+	"mp4": {
+		Name:                   "mp4",
+		Extension:              "mp4",
+		Muxer:                  "mp4",
+		MuxerArgs:              []string{"-movflags", "+faststart+use_metadata_tags"},
+		AudioCodec:             "aac",
+		AudioBitratePerChannel: 64_000,
+		CopyAudio:              map[string]bool{"aac": true, "opus": true},
+		SubtitleCodec:          "mov_text",
+		SupportsTimecode:       true,
+		SupportsCodecTag:       true,
+	},
+	"mkv": {
+		Name:                   "mkv",
+		Extension:              "mkv",
+		Muxer:                  "matroska",
+		AudioCodec:             "libopus",
+		AudioBitratePerChannel: 48_000,
+		CopyAudio:              map[string]bool{"opus": true},
+		SubtitleCodec:          "srt",
+		KeepBitmapSubtitles:    true,
+	},
+	"webm": {
+		Name:                   "webm",
+		Extension:              "webm",
+		Muxer:                  "webm",
+		AudioCodec:             "libopus",
+		AudioBitratePerChannel: 48_000,
+		CopyAudio:              map[string]bool{"opus": true},
+		SubtitleCodec:          "webvtt",
+	},
+}
+
+// videoOutputExtensions lists every extension a converted video can have, so
+// verification and recovery cover outputs from runs with other containers.
+var videoOutputExtensions = []string{"mp4", "mkv", "webm"}
+
+// outputContainer returns the container for a plan. WebM only holds AV1 among
+// the supported codecs, so other codecs chosen by rules fall back to MKV.
+func (c *Converter) outputContainer(inputPath string, plan conversionPlan) videoContainer {
+	container, ok := videoContainers[c.config.VideoContainer]
+	if !ok {
+		return videoContainers["mp4"]
+	}
+	if container.Name == "webm" && normalizeVideoCodec(plan.Codec) != "av1" {
+		c.logger.Warn(fmt.Sprintf("📹 %s: %s is not allowed in WebM, using MKV", filepath.Base(inputPath), plan.Codec))
+		return videoContainers["mkv"]
+	}
+	return container
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package converter
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchControlSignals pauses the queue on SIGUSR1 and resumes it on SIGUSR2
+// until the returned function is called.
+func (c *Converter) watchControlSignals() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				if sig == syscall.SIGUSR1 {
+					c.Pause()
+				} else {
+					c.Resume()
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// canSuspend reports whether running encodes can be suspended.
+const canSuspend = true
+
+// suspendProcess stops a process until resumeProcess continues it.
+func suspendProcess(process *os.Process) error {
+	return process.Signal(syscall.SIGSTOP)
+}
+
+func resumeProcess(process *os.Process) error {
+	return process.Signal(syscall.SIGCONT)
+}
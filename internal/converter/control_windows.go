@@ -0,0 +1,28 @@
+//go:build windows
+
+package converter
+
+import (
+	"errors"
+	"os"
+)
+
+// canSuspend is false on Windows, which has no SIGSTOP: running files finish
+// instead of being suspended outside the run windows.
+const canSuspend = false
+
+var errNoSuspend = errors.New("suspending processes is not supported on Windows")
+
+// watchControlSignals is a no-op: Windows has no user signals, the control
+// socket and the HTTP API remain available.
+func (c *Converter) watchControlSignals() func() {
+	return func() {}
+}
+
+func suspendProcess(process *os.Process) error {
+	return errNoSuspend
+}
+
+func resumeProcess(process *os.Process) error {
+	return errNoSuspend
+}
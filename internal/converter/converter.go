@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -23,39 +25,88 @@ type Converter struct {
 	ffmpegCommand []string
 	ffmpegMessage string
 	accelOnce     sync.Once
-	accelInfo     VideoAccelerationInfo
+	hwEncoders    map[string][]hardwareEncoder
+	color         colorSettings
+
+	// Encoders compiled into ffmpeg, listed on first use
+	encodersOnce sync.Once
+	encoders     map[string]bool
+
+	// Chapter-group role of queued videos, by path
+	chapters map[string]chapterJob
+
+	// Configuration warnings already logged
+	warned sync.Map
+
+	// Video progress callbacks
+	progressMu          sync.RWMutex
+	progressSubscribers []func(VideoProgress)
+
+	// Queued, active and finished files with the queue controls
+	jobs *jobTracker
+
+	// Labelled counters exported on /metrics
+	metrics *metrics
+
+	// Priority and limits of the encoder processes, nil when not configured
+	priority *processPriority
+
+	// Live terminal dashboard, nil when disabled
+	dashboard *dashboard
 }
 
 type ConversionStats struct {
-	mu              sync.Mutex
-	totalFiles      int
-	processedFiles  int
-	failedFiles     int
-	skippedFiles    int
-	recoveredFiles  int
-	cleanedFiles    int
-	verifiedFiles   int
-	startTime       time.Time
-	totalSizeMB     float64
-	processedSizeMB float64
-	outputSizeMB    float64
-	totalS3Cost     float64
-	savedSizeMB     float64
+	mu                sync.Mutex
+	totalFiles        int
+	processedFiles    int
+	failedFiles       int
+	skippedFiles      int
+	ruleSkippedFiles  int
+	copiedFiles       int
+	remuxedFiles      int
+	cancelledFiles    int
+	hdrPreserved      int
+	hdrTonemapped     int
+	metadataLossFiles int
+	deinterlacedFiles int
+	slowMotionLost    int
+	recoveredFiles    int
+	cleanedFiles      int
+	verifiedFiles     int
+	startTime         time.Time
+	totalSizeMB       float64
+	processedSizeMB   float64
+	outputSizeMB      float64
+	totalS3Cost       float64
+	savedSizeMB       float64
+	keptOriginals     []keptOriginal
+	rawMethods        map[string]int
+}
+
+// keptOriginal records a file whose encode was discarded for lack of savings.
+type keptOriginal struct {
+	name    string
+	savings float64
 }
 
 func NewConverter(cfg *config.Config, log *logger.Logger) *Converter {
 	ffmpegCmd, ffmpegMsg := utils.ResolveFFmpegCommand()
 
-	return &Converter{
+	c := &Converter{
 		config:   cfg,
 		logger:   log,
 		security: security.NewSecurityChecker(cfg.MinOutputSizeRatio, cfg.MinOutputSizeRatioAVIF, cfg.MinOutputSizeRatioWebP),
 		stats: &ConversionStats{
-			startTime: time.Now(),
+			startTime:  time.Now(),
+			rawMethods: make(map[string]int),
 		},
 		ffmpegCommand: ffmpegCmd,
 		ffmpegMessage: ffmpegMsg,
+		jobs:          newJobTracker(),
+		metrics:       newMetrics(),
 	}
+	c.OnVideoProgress(c.jobs.update)
+	return c
 }
 
 func (c *Converter) Convert() error {
@@ -78,6 +129,21 @@ func (c *Converter) Convert() error {
 		c.logger.Warn(fmt.Sprintf("Recovery issues detected: %v", err))
 	}
 
+	// Make sure the RAW backend is available before touching any file
+	if err := c.checkRawBackend(); err != nil {
+		return fmt.Errorf("RAW backend check failed: %w", err)
+	}
+
+	if err := c.checkColorManagement(); err != nil {
+		return fmt.Errorf("colour management setup failed: %w", err)
+	}
+
+	// Encoders started from here on run with the configured priority
+	if !c.config.DryRun {
+		c.setupProcessPriority()
+		defer c.closeProcessPriority()
+	}
+
 	// Check disk space
 	if err := c.security.CheckDiskSpace(c.config.SourceDir, c.config.DestDir); err != nil {
 		return fmt.Errorf("disk space check failed: %w", err)
@@ -97,14 +163,40 @@ func (c *Converter) Convert() error {
 		return fmt.Errorf("failed to find files: %w", err)
 	}
 
+	// Calculate total file size
+	c.calculateTotalSize(append(photoFiles, videoFiles...))
+
+	// Chapters of one recording are converted together
+	videoFiles = c.planChapterJobs(videoFiles)
+
+	c.stats.mu.Lock()
 	c.stats.totalFiles = len(photoFiles) + len(videoFiles)
+	c.stats.mu.Unlock()
 	c.logger.Info(fmt.Sprintf("📸 Photos found: %d", len(photoFiles)))
 	c.logger.Info(fmt.Sprintf("🎬 Videos found: %d", len(videoFiles)))
 	c.logger.Info(fmt.Sprintf("📁 Total files: %d", c.stats.totalFiles))
 	fmt.Println()
 
-	// Calculate total file size
-	c.calculateTotalSize(append(photoFiles, videoFiles...))
+	// The dashboard replaces the log lines while files are converted
+	if c.config.Dashboard {
+		if logger.IsTerminal() {
+			c.dashboard = newDashboard(c, os.Stdout)
+			c.dashboard.start()
+		} else {
+			c.logger.Info("Output is not a terminal, showing log lines instead of the dashboard")
+		}
+	}
+
+	// SIGUSR1 pauses the queue, SIGUSR2 resumes it
+	stopSignals := c.watchControlSignals()
+	defer stopSignals()
+
+	// Run windows hold the queue outside the configured hours
+	if c.config.Schedule.Enabled() {
+		ctx, stopSchedule := context.WithCancel(context.Background())
+		defer stopSchedule()
+		go c.runSchedule(ctx)
+	}
 
 	// Convert files
 	if len(photoFiles) > 0 {
@@ -115,13 +207,19 @@ func (c *Converter) Convert() error {
 	}
 
 	if len(videoFiles) > 0 {
-		fmt.Println()
+		if c.dashboard == nil {
+			fmt.Println()
+		}
 		c.logger.Log("Converting videos...")
 		if err := c.convertFiles(videoFiles, "video"); err != nil {
 			c.logger.Error(fmt.Sprintf("Video conversion failed: %v", err))
 		}
 	}
 
+	if c.dashboard != nil {
+		c.dashboard.stop()
+	}
+
 	// Show final report
 	c.showFinalReport()
 
@@ -140,7 +238,7 @@ func (c *Converter) findFiles() ([]string, []string, error) {
 		}
 
 		if info.IsDir() {
-			if utils.ShouldSkipSystemEntry(info.Name(), true) {
+			if utils.ShouldSkipSystemEntry(info.Name(), true) || strings.HasSuffix(path, segmentDirSuffix) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -174,6 +272,7 @@ func (c *Converter) convertFiles(files []string, fileType string) error {
 
 	var (
 		limiter      *AdaptiveLimiter
+		adaptive     bool
 		cancelAdjust context.CancelFunc
 	)
 
@@ -196,10 +295,14 @@ func (c *Converter) convertFiles(files []string, fileType string) error {
 			}
 
 			limiter = NewAdaptiveLimiter(initialLimit)
+			adaptive = true
 			ctx, cancel := context.WithCancel(context.Background())
 			cancelAdjust = cancel
 			monitor := NewResourceMonitor(c.config.AdaptiveWorkers.CheckInterval, c.logger)
-			snapshots := monitor.Start(ctx)
+			if c.priority != nil {
+				monitor.budget = c.priority.budget
+			}
+			snapshots := c.metrics.observeResources(monitor.Start(ctx))
 			go runAdaptiveController(ctx, limiter, c.config.AdaptiveWorkers, snapshots, c.logger)
 		} else {
 			if maxJobs > 2 {
@@ -209,29 +312,61 @@ func (c *Converter) convertFiles(files []string, fileType string) error {
 		}
 	}
 
-	jobs := make(chan string)
+	// Every phase runs behind a limiter so the concurrency can be changed
+	// at runtime. Idle workers wait for a slot up to the highest limit.
+	if limiter == nil {
+		limiter = NewAdaptiveLimiter(maxJobs)
+	}
+	workers := maxJobs
+	if workers < runtime.NumCPU() {
+		workers = runtime.NumCPU()
+	}
+	c.jobs.startPhase(fileType, files, limiter, adaptive, maxJobs, workers)
+
 	var wg sync.WaitGroup
 
 	worker := func() {
 		defer wg.Done()
-		for filePath := range jobs {
-			if limiter != nil {
-				limiter.Acquire()
+		for {
+			c.jobs.waitWhilePaused()
+			limiter.Acquire()
+			filePath, more := c.jobs.next()
+			if filePath == "" {
+				limiter.Release()
+				if !more {
+					return
+				}
+				continue
 			}
 
-			if err := c.convertFile(filePath, fileType); err != nil {
-				if limiter != nil {
-					limiter.Release()
-				}
-				c.logger.Error(fmt.Sprintf("Failed to convert %s: %v", filepath.Base(filePath), err))
+			started := time.Now()
+			var err error
+			if c.jobs.begin(filePath, fileType) {
+				err = c.convertFile(filePath, fileType)
+			} else {
+				err = errCancelled
+			}
+			record := c.jobs.finish(filePath, fileType, started, err)
+			c.metrics.fileFinished()
+
+			limiter.Release()
+
+			if record.Status == FileCancelled {
+				c.logger.Warn(fmt.Sprintf("⏹️  %s cancelled", filepath.Base(filePath)))
 				c.stats.mu.Lock()
-				c.stats.failedFiles++
+				c.stats.cancelledFiles++
 				c.stats.mu.Unlock()
+				c.metrics.fileEvent(eventCancelled, fileType)
 				continue
 			}
 
-			if limiter != nil {
-				limiter.Release()
+			if err != nil {
+				c.logger.Error(fmt.Sprintf("Failed to convert %s: %v", filepath.Base(filePath), err))
+				c.stats.mu.Lock()
+				c.stats.failedFiles++
+				c.stats.mu.Unlock()
+				c.metrics.fileEvent(eventFailed, fileType)
+				continue
 			}
 
 			c.stats.mu.Lock()
@@ -245,16 +380,10 @@ func (c *Converter) convertFiles(files []string, fileType string) error {
 		}
 	}
 
-	for i := 0; i < maxJobs; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go worker()
 	}
-
-	for _, file := range files {
-		jobs <- file
-	}
-	close(jobs)
-
 	wg.Wait()
 
 	if cancelAdjust != nil {
@@ -279,7 +408,7 @@ func (c *Converter) runSafetyTest() error {
 		}
 
 		if info.IsDir() {
-			if utils.ShouldSkipSystemEntry(info.Name(), true) {
+			if utils.ShouldSkipSystemEntry(info.Name(), true) || strings.HasSuffix(path, segmentDirSuffix) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -434,6 +563,9 @@ func (c *Converter) copyFile(src, dst string) error {
 	return err
 }
 
+// maxReportedKeptOriginals caps the kept-original list in the final report.
+const maxReportedKeptOriginals = 20
+
 func (c *Converter) showFinalReport() {
 	duration := time.Since(c.stats.startTime)
 
@@ -449,6 +581,57 @@ func (c *Converter) showFinalReport() {
 		c.logger.Info(fmt.Sprintf("⏭️  Files skipped (already exist): %d", c.stats.skippedFiles))
 	}
 
+	if c.stats.ruleSkippedFiles > 0 {
+		c.logger.Info(fmt.Sprintf("⏭️  Files skipped by routing rules: %d", c.stats.ruleSkippedFiles))
+	}
+
+	if c.stats.cancelledFiles > 0 {
+		c.logger.Warn(fmt.Sprintf("⏹️  Files cancelled: %d", c.stats.cancelledFiles))
+	}
+
+	if c.stats.copiedFiles > 0 {
+		c.logger.Info(fmt.Sprintf("📋 Files copied without re-encoding: %d", c.stats.copiedFiles))
+	}
+
+	if len(c.stats.keptOriginals) > 0 {
+		c.logger.Info(fmt.Sprintf("📋 Originals kept (encode not smaller): %d", len(c.stats.keptOriginals)))
+		for i, kept := range c.stats.keptOriginals {
+			if i == maxReportedKeptOriginals {
+				c.logger.Info(fmt.Sprintf("   … and %d more (see conversion.log)", len(c.stats.keptOriginals)-i))
+				break
+			}
+			c.logger.Info(fmt.Sprintf("   • %s (%+.1f%% size)", kept.name, -kept.savings))
+		}
+	}
+
+	if c.stats.remuxedFiles > 0 {
+		c.logger.Info(fmt.Sprintf("📦 Files remuxed without re-encoding: %d", c.stats.remuxedFiles))
+	}
+
+	if c.stats.metadataLossFiles > 0 {
+		c.logger.Warn(fmt.Sprintf("🏷️  Videos with lost metadata tags: %d (see conversion.log)", c.stats.metadataLossFiles))
+	}
+
+	if c.stats.hdrPreserved > 0 || c.stats.hdrTonemapped > 0 {
+		c.logger.Info(fmt.Sprintf("🌈 HDR videos: %d preserved, %d tone-mapped to SDR", c.stats.hdrPreserved, c.stats.hdrTonemapped))
+	}
+
+	if c.stats.deinterlacedFiles > 0 {
+		c.logger.Info(fmt.Sprintf("🎞️  Interlaced videos deinterlaced: %d", c.stats.deinterlacedFiles))
+	}
+	if c.stats.slowMotionLost > 0 {
+		c.logger.Warn(fmt.Sprintf("🐢 High-speed videos that lost their slow-motion timing: %d (see conversion.log)", c.stats.slowMotionLost))
+	}
+
+	if len(c.stats.rawMethods) > 0 {
+		methods := make([]string, 0, len(c.stats.rawMethods))
+		for method, count := range c.stats.rawMethods {
+			methods = append(methods, fmt.Sprintf("%s %d", method, count))
+		}
+		sort.Strings(methods)
+		c.logger.Info(fmt.Sprintf("🎞️  RAW files developed: %s", strings.Join(methods, ", ")))
+	}
+
 	if c.stats.recoveredFiles > 0 {
 		c.logger.Info(fmt.Sprintf("🔄 Files recovered from corruption: %d", c.stats.recoveredFiles))
 	}
@@ -499,7 +682,15 @@ func (c *Converter) showFinalReport() {
 	}
 }
 
-func (c *Converter) updateSizeStats(inputSizeMB, outputSizeMB float64) {
+// updateSizeStats records a file written to the destination. job is the
+// queued path the conversion started from.
+func (c *Converter) updateSizeStats(job, fileType, codec string, inputSizeMB, outputSizeMB float64) {
+	var duration time.Duration
+	if started, ok := c.jobs.startedAt(job); ok {
+		duration = time.Since(started)
+	}
+	c.metrics.fileConverted(fileType, codec, inputSizeMB*1024*1024, outputSizeMB*1024*1024, duration)
+
 	c.stats.mu.Lock()
 	defer c.stats.mu.Unlock()
 
@@ -553,6 +744,10 @@ func (c *Converter) verifyExistingFiles() error {
 		}
 
 		if info.IsDir() {
+			// Unfinished segmented encodes are resumed, not verified
+			if strings.HasSuffix(path, segmentDirSuffix) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -565,6 +760,7 @@ func (c *Converter) verifyExistingFiles() error {
 				c.stats.mu.Lock()
 				c.stats.recoveredFiles++
 				c.stats.mu.Unlock()
+				c.metrics.fileEvent(eventRecovered, "photo")
 			} else {
 				c.stats.mu.Lock()
 				c.stats.verifiedFiles++
@@ -572,8 +768,8 @@ func (c *Converter) verifyExistingFiles() error {
 			}
 		}
 
-		// Check converted video files (mp4)
-		if strings.HasSuffix(strings.ToLower(path), ".mp4") &&
+		// Check converted video files (any output container)
+		if utils.HasExtension(path, videoOutputExtensions) &&
 			strings.Contains(strings.ToLower(path), "_") { // Only check converted files (with date prefix)
 			if c.security.IsFileCorrupted(path, "video") {
 				c.logger.Warn(fmt.Sprintf("🔍 Corrupted video detected: %s (will be re-converted)", filepath.Base(path)))
@@ -581,6 +777,7 @@ func (c *Converter) verifyExistingFiles() error {
 				c.stats.mu.Lock()
 				c.stats.recoveredFiles++
 				c.stats.mu.Unlock()
+				c.metrics.fileEvent(eventRecovered, "video")
 			} else {
 				c.stats.mu.Lock()
 				c.stats.verifiedFiles++
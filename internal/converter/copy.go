@@ -0,0 +1,352 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// copyOriginal places the untouched source file into the organised tree,
+// keeping its original extension. The reason is shown in the log line.
+func (c *Converter) copyOriginal(inputPath, fileType, reason string) error {
+	return c.placeOriginal(inputPath, fileType, reason, false)
+}
+
+// placeOriginal copies a source into the organised tree. Kept originals,
+// whose encode was discarded, are reported in keptOriginals by the caller
+// rather than counted as copies.
+func (c *Converter) placeOriginal(inputPath, fileType, reason string, kept bool) error {
+	filename := filepath.Base(inputPath)
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+
+	fileDate, err := utils.GetFileDate(inputPath)
+	if err != nil {
+		c.logger.Warn(fmt.Sprintf("Could not extract date from %s: %v - skipping file", filename, err))
+		return fmt.Errorf("unable to determine file date: %w", err)
+	}
+
+	mediaDir := "image"
+	if fileType == "video" {
+		mediaDir = "video"
+	}
+
+	destPath := utils.CreateDestinationPath(c.config.DestDir, fileDate, mediaDir, c.config.OrganizeByDate, c.config.Language)
+	if err := utils.EnsureDir(destPath); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	inputInfo, err := os.Stat(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat input file: %w", err)
+	}
+
+	// Sources sharing a name and date get the next counter; an identical copy
+	// from a previous run is kept as-is
+	var cleanName, outputPath string
+	for {
+		existing, free := findOriginalCopy(inputPath, destPath, name, ext, fileDate)
+		if existing != "" {
+			c.logger.Info(fmt.Sprintf("📋 %s -> %s (already copied, skipping)", filename, existing))
+			c.stats.mu.Lock()
+			c.stats.skippedFiles++
+			c.stats.mu.Unlock()
+			c.metrics.fileEvent(eventSkipped, fileType)
+			return nil
+		}
+		cleanName, outputPath = free, filepath.Join(destPath, free)
+
+		if c.config.DryRun {
+			c.logger.Info(fmt.Sprintf("[DRY-RUN] Would copy: %s → %s (%s)", filename, cleanName, reason))
+			return nil
+		}
+
+		// Reserve the name so a concurrent copy of a same-named source
+		// picks another counter instead of replacing this one
+		reserved, err := os.OpenFile(outputPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to reserve %s: %w", cleanName, err)
+		}
+		reserved.Close()
+		break
+	}
+	finalized := false
+	defer func() {
+		if !finalized {
+			os.Remove(outputPath)
+		}
+	}()
+
+	tempPath := outputPath + ".tmp"
+	defer func() {
+		if _, err := os.Stat(tempPath); err == nil {
+			os.Remove(tempPath)
+		}
+	}()
+
+	if err := c.copyFile(inputPath, tempPath); err != nil {
+		return fmt.Errorf("copy failed: %w", err)
+	}
+
+	// Keep the original modification time so date detection stays stable
+	os.Chtimes(tempPath, inputInfo.ModTime(), inputInfo.ModTime())
+
+	// Replaces only the empty file reserved above
+	if err := os.Rename(tempPath, outputPath); err != nil {
+		return fmt.Errorf("failed to finalize copy: %w", err)
+	}
+	finalized = true
+
+	sizeMB := float64(inputInfo.Size()) / (1024 * 1024)
+	c.logger.Success(fmt.Sprintf("📋 %s -> %s | copied (%s, %.1f MB)", filename, cleanName, reason, sizeMB))
+
+	if kept {
+		c.updateSizeStats(inputPath, fileType, "original", sizeMB, sizeMB)
+	} else {
+		c.updateSizeStats(inputPath, fileType, "copy", sizeMB, sizeMB)
+		c.stats.mu.Lock()
+		c.stats.copiedFiles++
+		c.stats.mu.Unlock()
+	}
+
+	if !c.config.KeepOriginals {
+		if err := c.security.SafeDelete(inputPath, outputPath); err != nil {
+			c.logger.Warn(fmt.Sprintf("Deletion cancelled for safety: %s (%v)", filename, err))
+		} else {
+			c.logger.Security(fmt.Sprintf("Safe deletion: %s", filename))
+		}
+	}
+
+	return nil
+}
+
+// remuxOriginal rewrites an HEVC source into MP4 without re-encoding, adding
+// the hvc1 tag and moving the moov atom to the front for streaming.
+func (c *Converter) remuxOriginal(inputPath, reason string) error {
+	filename := filepath.Base(inputPath)
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	fileDate, err := utils.GetFileDate(inputPath)
+	if err != nil {
+		c.logger.Warn(fmt.Sprintf("Could not extract date from %s: %v - skipping file", filename, err))
+		return fmt.Errorf("unable to determine file date: %w", err)
+	}
+
+	destPath := utils.CreateDestinationPath(c.config.DestDir, fileDate, "video", c.config.OrganizeByDate, c.config.Language)
+	if err := utils.EnsureDir(destPath); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	cleanName := utils.CleanFilename(name, "mp4", fileDate, 1)
+	outputPath := filepath.Join(destPath, cleanName)
+
+	if _, err := os.Stat(outputPath); err == nil {
+		if !c.security.IsFileCorrupted(outputPath, "video") {
+			c.logger.Info(fmt.Sprintf("📹 %s -> %s (already exists and valid, skipping)", filename, cleanName))
+			c.stats.mu.Lock()
+			c.stats.skippedFiles++
+			c.stats.mu.Unlock()
+			c.metrics.fileEvent(eventSkipped, "video")
+			return nil
+		}
+		c.logger.Warn(fmt.Sprintf("📹 %s -> %s (corrupted file detected, re-muxing)", filename, cleanName))
+		os.Remove(outputPath)
+		c.stats.mu.Lock()
+		c.stats.recoveredFiles++
+		c.stats.mu.Unlock()
+		c.metrics.fileEvent(eventRecovered, "video")
+	}
+
+	if c.config.DryRun {
+		c.logger.Info(fmt.Sprintf("[DRY-RUN] Would remux: %s → %s (%s)", filename, cleanName, reason))
+		return nil
+	}
+
+	tempPath := outputPath + ".tmp"
+	if err := c.security.CreateProcessingMarker(outputPath); err != nil {
+		c.logger.Warn(fmt.Sprintf("Failed to create processing marker: %v", err))
+	}
+	defer func() {
+		c.security.RemoveProcessingMarker(outputPath)
+		if _, err := os.Stat(tempPath); err == nil {
+			os.Remove(tempPath)
+		}
+	}()
+
+	ctx, cancel := c.encodeContext(context.Background(), inputPath, c.config.ConversionTimeoutVideo)
+	defer cancel()
+
+	probe, _ := utils.ProbeMedia(inputPath) // without a probe, video and audio are copied as-is
+	cmd := c.newFFmpegCommand(ctx, remuxArgs(inputPath, tempPath, probe)...)
+
+	var stderrBuf strings.Builder
+	cmd.Stderr = &stderrBuf
+	if err := c.runProcess(inputPath, cmd); err != nil {
+		if c.jobs.isCancelled(inputPath) {
+			os.Remove(tempPath)
+			return fmt.Errorf("remux failed: %w", err)
+		}
+		// The original is still intact: keep it rather than failing the file
+		c.logger.Warn(fmt.Sprintf("📦 %s: remux failed, copying the original instead (%v - %s)", filename, err, strings.TrimSpace(stderrBuf.String())))
+		os.Remove(tempPath)
+		return c.copyOriginal(inputPath, "video", reason)
+	}
+
+	if err := c.security.VerifyOutputFile(inputPath, tempPath, "video", "mp4"); err != nil {
+		return fmt.Errorf("output verification failed: %w", err)
+	}
+
+	if err := os.Rename(tempPath, outputPath); err != nil {
+		return fmt.Errorf("failed to finalize remux: %w", err)
+	}
+
+	originalInfo, _ := os.Stat(inputPath)
+	newInfo, _ := os.Stat(outputPath)
+	originalSizeMB := float64(originalInfo.Size()) / (1024 * 1024)
+	newSizeMB := float64(newInfo.Size()) / (1024 * 1024)
+
+	c.logger.Success(fmt.Sprintf("📦 %s -> %s | remuxed (%s, %.1f->%.1f MB)", filename, cleanName, reason, originalSizeMB, newSizeMB))
+
+	c.updateSizeStats(inputPath, "video", "hevc", originalSizeMB, newSizeMB)
+	c.stats.mu.Lock()
+	c.stats.remuxedFiles++
+	c.stats.mu.Unlock()
+
+	if !c.config.KeepOriginals {
+		if err := c.security.SafeDelete(inputPath, outputPath); err != nil {
+			c.logger.Warn(fmt.Sprintf("Deletion cancelled for safety: %s (%v)", filename, err))
+		} else {
+			c.logger.Security(fmt.Sprintf("Safe deletion: %s", filename))
+		}
+	}
+
+	return nil
+}
+
+// remuxArgs copies the video stream of an HEVC source into MP4. Audio MP4
+// cannot hold (LPCM in camera MOVs) is re-encoded to AAC and data tracks
+// other than timecode are left out, as in a full encode. Without a probe
+// only the video and audio are copied.
+func remuxArgs(inputPath, tempPath string, probe *utils.MediaProbe) []string {
+	args := []string{"-i", inputPath}
+	if probe != nil {
+		mapping := buildStreamMapping(probe, videoContainers["mp4"], 0)
+		args = append(args, mapping.VideoArgs...)
+		args = append(args, "-c:v", "copy")
+		args = append(args, mapping.Args...)
+	} else {
+		args = append(args, "-map", "0:v:0", "-map", "0:a?", "-c", "copy")
+	}
+	return append(args,
+		"-tag:v", "hvc1",
+		"-movflags", "+faststart+use_metadata_tags",
+		"-map_metadata", "0",
+		"-f", "mp4",
+		"-y", tempPath,
+	)
+}
+
+// meetsMinimumSavings compares an encoded file against its source. It returns
+// the achieved savings in percent and whether they satisfy the configured minimum.
+func (c *Converter) meetsMinimumSavings(inputPath, encodedPath string) (float64, bool) {
+	if c.config.MinSavingsPercent < 0 {
+		return 0, true
+	}
+
+	inputInfo, err := os.Stat(inputPath)
+	if err != nil || inputInfo.Size() == 0 {
+		return 0, true
+	}
+	outputInfo, err := os.Stat(encodedPath)
+	if err != nil {
+		return 0, true
+	}
+
+	savings := float64(inputInfo.Size()-outputInfo.Size()) * 100 / float64(inputInfo.Size())
+	if outputInfo.Size() >= inputInfo.Size() || savings < c.config.MinSavingsPercent {
+		return savings, false
+	}
+	return savings, true
+}
+
+// keepOriginalInstead discards an encode that did not save enough space and
+// copies the source into the organised tree in its place.
+func (c *Converter) keepOriginalInstead(inputPath, encodedPath, fileType string, savings float64) error {
+	os.Remove(encodedPath)
+
+	c.stats.mu.Lock()
+	c.stats.keptOriginals = append(c.stats.keptOriginals, keptOriginal{
+		name:    filepath.Base(inputPath),
+		savings: savings,
+	})
+	c.stats.mu.Unlock()
+
+	reason := fmt.Sprintf("encode saved %.1f%%, minimum %.1f%%", savings, c.config.MinSavingsPercent)
+	return c.placeOriginal(inputPath, fileType, reason, true)
+}
+
+// findKeptOriginal looks for an original copied by a previous run because its
+// encode was not smaller, so the file is not re-encoded on every run.
+func (c *Converter) findKeptOriginal(inputPath, destPath, name string, fileDate time.Time) (string, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(inputPath), "."))
+	existing, _ := findOriginalCopy(inputPath, destPath, name, ext, fileDate)
+	return existing, existing != ""
+}
+
+// findOriginalCopy walks the counters of a copied original's name. It returns
+// the name holding an identical copy, or else the first free name.
+func findOriginalCopy(inputPath, destPath, name, ext string, fileDate time.Time) (existing, free string) {
+	for counter := 1; ; counter++ {
+		candidate := utils.CleanFilename(name, ext, fileDate, counter)
+		if _, err := os.Stat(filepath.Join(destPath, candidate)); err != nil {
+			return "", candidate
+		}
+		if sameContent(inputPath, filepath.Join(destPath, candidate)) {
+			return candidate, ""
+		}
+	}
+}
+
+// sameContent reports whether two files have the same size and bytes.
+func sameContent(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil || infoA.Size() != infoB.Size() {
+		return false
+	}
+
+	fileA, err := os.Open(a)
+	if err != nil {
+		return false
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(b)
+	if err != nil {
+		return false
+	}
+	defer fileB.Close()
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		n, errA := io.ReadFull(fileA, bufA)
+		m, errB := io.ReadFull(fileB, bufB)
+		if n != m || !bytes.Equal(bufA[:n], bufB[:m]) {
+			return false
+		}
+		if errA != nil || errB != nil {
+			return errA == errB // both reached the end together
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/config"
+	"github.com/kevindurb/media-converter/internal/logger"
+	"github.com/kevindurb/media-converter/internal/security"
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// newCopyTestConverter returns a converter writing into a flat destination.
+func newCopyTestConverter(t *testing.T) *Converter {
+	t.Helper()
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "conversion.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Converter{
+		config:   &config.Config{DestDir: t.TempDir(), KeepOriginals: true, MinSavingsPercent: 10},
+		logger:   log,
+		security: security.NewSecurityChecker(0, 0, 0),
+		stats:    &ConversionStats{startTime: time.Now()},
+		jobs:     newJobTracker(),
+		metrics:  newMetrics(),
+	}
+}
+
+// writeSource writes a source file dated 2024-03-01.
+func writeSource(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(path, date, date); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyOriginalSameNameSources(t *testing.T) {
+	c := newCopyTestConverter(t)
+	src := t.TempDir()
+	first := filepath.Join(src, "card1", "IMG_0001.JPG")
+	second := filepath.Join(src, "card2", "IMG_0001.JPG")
+	writeSource(t, first, "first card")
+	writeSource(t, second, "other card") // same size, different bytes
+
+	for _, path := range []string{first, second, first, second} {
+		if err := c.copyOriginal(path, "photo", "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	destPath := filepath.Join(c.config.DestDir, "images")
+	entries, err := os.ReadDir(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected two copies, got %d", len(entries))
+	}
+	for i, want := range []string{"first card", "other card"} {
+		data, err := os.ReadFile(filepath.Join(destPath, entries[i].Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: expected %q, got %q", entries[i].Name(), want, data)
+		}
+	}
+	if c.stats.copiedFiles != 2 || c.stats.skippedFiles != 2 {
+		t.Errorf("expected 2 copied and 2 skipped, got %d and %d", c.stats.copiedFiles, c.stats.skippedFiles)
+	}
+}
+
+func TestRemuxArgsReencodesUnsupportedAudio(t *testing.T) {
+	probe := &utils.MediaProbe{
+		Streams: []utils.ProbeStream{
+			{Index: 0, CodecType: "video", CodecName: "hevc"},
+			{Index: 1, CodecType: "audio", CodecName: "pcm_s24le", Channels: 2},
+			{Index: 2, CodecType: "data", CodecTagString: "gpmd", Tags: map[string]string{"handler_name": "GoPro MET"}},
+		},
+	}
+
+	args := strings.Join(remuxArgs("in.mov", "out.tmp", probe), " ")
+	for _, want := range []string{"-map 0:0 -c:v copy", "-map 0:1 -c:a:0 aac", "-tag:v hvc1", "-f mp4 -y out.tmp"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in remux arguments, got %q", want, args)
+		}
+	}
+	if strings.Contains(args, "0:2") || strings.Contains(args, "-c copy") {
+		t.Errorf("data tracks must not be mapped or stream-copied: %q", args)
+	}
+}
+
+func TestKeepOriginalBelowMinimumSavings(t *testing.T) {
+	c := newCopyTestConverter(t)
+	src := t.TempDir()
+	input := filepath.Join(src, "IMG_0002.PNG")
+	writeSource(t, input, strings.Repeat("x", 100))
+
+	small := filepath.Join(src, "small.avif")
+	writeSource(t, small, strings.Repeat("y", 50))
+	if savings, ok := c.meetsMinimumSavings(input, small); !ok || savings != 50 {
+		t.Errorf("expected 50%% savings to pass, got %.1f%% (%v)", savings, ok)
+	}
+
+	encoded := filepath.Join(src, "encoded.avif")
+	writeSource(t, encoded, strings.Repeat("y", 95))
+	savings, ok := c.meetsMinimumSavings(input, encoded)
+	if ok {
+		t.Fatalf("expected %.1f%% savings to miss the 10%% minimum", savings)
+	}
+	if err := c.keepOriginalInstead(input, encoded, "photo", savings); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(encoded); !os.IsNotExist(err) {
+		t.Error("the discarded encode should be removed")
+	}
+	kept := filepath.Join(c.config.DestDir, "images", "2024-03-01_IMG_0002_001.png")
+	if !sameContent(input, kept) {
+		t.Errorf("expected the original at %s", kept)
+	}
+	if len(c.stats.keptOriginals) != 1 || c.stats.copiedFiles != 0 {
+		t.Errorf("expected 1 kept original and no copies, got %d and %d", len(c.stats.keptOriginals), c.stats.copiedFiles)
+	}
+}
@@ -0,0 +1,285 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// Dashboard refresh period and size of the recent messages pane
+const (
+	dashboardRefresh   = 500 * time.Millisecond
+	dashboardRecentMax = 5
+)
+
+// dashboard draws a live view of the running jobs on the terminal's
+// alternate screen. Console log lines are redirected into it while it runs;
+// the log file keeps every line.
+type dashboard struct {
+	c   *Converter
+	out io.Writer
+
+	mu       sync.Mutex
+	recent   []string
+	stopOnce sync.Once
+	quit     chan struct{}
+	done     chan struct{}
+	signals  chan os.Signal
+}
+
+// dashboardView is a snapshot of what the dashboard shows.
+type dashboardView struct {
+	Phase       string
+	Processed   int
+	Failed      int
+	Total       int
+	Elapsed     time.Duration
+	ProcessedMB float64
+	SavedMB     float64
+	Limit       int // adaptive limit, 0 without the adaptive limiter
+	Active      int
+	MaxJobs     int
+	Rows        []dashboardRowView
+	Recent      []string
+}
+
+// dashboardRowView is the displayed state of one active job.
+type dashboardRowView struct {
+	File    string
+	Encoder string
+	Percent float64 // negative when unknown
+	Speed   float64
+	ETA     time.Duration // 0 when unknown
+}
+
+func newDashboard(c *Converter, out io.Writer) *dashboard {
+	return &dashboard{
+		c:    c,
+		out:  out,
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// start switches to the alternate screen, redirects the console log lines
+// and redraws until stop is called.
+func (d *dashboard) start() {
+	d.c.logger.Redirect(d.record)
+
+	// Restore the terminal when interrupted
+	d.signals = make(chan os.Signal, 1)
+	signal.Notify(d.signals, os.Interrupt, syscall.SIGTERM)
+
+	fmt.Fprint(d.out, "\033[?1049h\033[?25l")
+	go d.loop()
+}
+
+func (d *dashboard) loop() {
+	defer close(d.done)
+	ticker := time.NewTicker(dashboardRefresh)
+	defer ticker.Stop()
+	for {
+		d.draw()
+		select {
+		case <-d.quit:
+			return
+		case <-d.signals:
+			d.restore()
+			os.Exit(130)
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop leaves the alternate screen and sends log lines to stdout again.
+func (d *dashboard) stop() {
+	d.stopOnce.Do(func() {
+		close(d.quit)
+		<-d.done
+		d.restore()
+	})
+}
+
+func (d *dashboard) restore() {
+	signal.Stop(d.signals)
+	fmt.Fprint(d.out, "\033[?25h\033[?1049l")
+	d.c.logger.Redirect(nil)
+}
+
+// record keeps the recent warnings and errors of the redirected log.
+func (d *dashboard) record(level, message string) {
+	var prefix string
+	switch level {
+	case "error", "security":
+		prefix = "✗ "
+	case "warn":
+		prefix = "⚠ "
+	default:
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recent = append(d.recent, prefix+message)
+	if len(d.recent) > dashboardRecentMax {
+		d.recent = d.recent[len(d.recent)-dashboardRecentMax:]
+	}
+}
+
+// view collects the dashboard state.
+func (d *dashboard) view() dashboardView {
+	var view dashboardView
+
+	d.c.stats.mu.Lock()
+	view.Processed = d.c.stats.processedFiles
+	view.Failed = d.c.stats.failedFiles
+	view.Total = d.c.stats.totalFiles
+	view.Elapsed = time.Since(d.c.stats.startTime)
+	view.ProcessedMB = d.c.stats.processedSizeMB
+	view.SavedMB = d.c.stats.savedSizeMB
+	d.c.stats.mu.Unlock()
+
+	limits := d.c.jobs.limits()
+	view.MaxJobs = limits.Limit
+	view.Active = limits.Active
+	if limits.Adaptive {
+		view.Limit = limits.Limit
+		view.MaxJobs = limits.MaxJobs
+	}
+
+	d.c.jobs.mu.Lock()
+	view.Phase = d.c.jobs.phase
+	d.c.jobs.mu.Unlock()
+
+	for _, job := range d.c.jobs.jobs() {
+		rowView := dashboardRowView{File: filepath.Base(job.Path), Encoder: job.Type, Percent: job.Percent, Speed: job.Speed}
+		if job.Encoder != "" {
+			rowView.Encoder = job.Encoder
+		}
+		rowView.ETA = time.Duration(job.ETA * float64(time.Second))
+		view.Rows = append(view.Rows, rowView)
+	}
+
+	d.mu.Lock()
+	view.Recent = append([]string{}, d.recent...)
+	d.mu.Unlock()
+	return view
+}
+
+func (d *dashboard) draw() {
+	width, height := terminalSize()
+	lines := d.c.renderDashboard(d.view(), width, height)
+
+	var frame strings.Builder
+	frame.WriteString("\033[H")
+	for _, line := range lines {
+		frame.WriteString(line)
+		frame.WriteString("\033[K\n")
+	}
+	frame.WriteString("\033[J")
+	fmt.Fprint(d.out, frame.String())
+}
+
+// renderDashboard lays out a dashboard view for a terminal of the given size.
+func (c *Converter) renderDashboard(view dashboardView, width, height int) []string {
+	if width <= 0 {
+		width = 100
+	}
+	if height <= 0 {
+		height = 30
+	}
+
+	var lines []string
+	title := "Media Converter"
+	if view.Phase != "" {
+		title += " - converting " + view.Phase
+	}
+	lines = append(lines, fmt.Sprintf("%s  (%s elapsed)", title, c.formatDuration(view.Elapsed)))
+
+	// Overall progress
+	done := view.Processed + view.Failed
+	percent := 0.0
+	if view.Total > 0 {
+		percent = float64(done) / float64(view.Total) * 100
+	}
+	overall := fmt.Sprintf("Overall %s %d/%d (%.1f%%)", progressBar(percent, 25), done, view.Total, percent)
+	if view.Failed > 0 {
+		overall += fmt.Sprintf("  %d failed", view.Failed)
+	}
+	if done > 0 && done < view.Total {
+		remaining := time.Duration(view.Total-done) * (view.Elapsed / time.Duration(done))
+		overall += "  ETA " + c.formatDuration(remaining)
+	}
+	lines = append(lines, overall)
+
+	savings := fmt.Sprintf("Saved %.1f MB", view.SavedMB)
+	if view.ProcessedMB > 0 {
+		savings += fmt.Sprintf(" of %.1f MB (%.1f%%)", view.ProcessedMB, view.SavedMB/view.ProcessedMB*100)
+	}
+	workers := fmt.Sprintf("Workers %d active / %d", view.Active, view.MaxJobs)
+	if view.Limit > 0 {
+		workers = fmt.Sprintf("Workers %d active / adaptive limit %d (max %d)", view.Active, view.Limit, view.MaxJobs)
+	}
+	lines = append(lines, savings+"   "+workers, "")
+
+	// One row per active job, as many as fit above the recent messages
+	lines = append(lines, fmt.Sprintf("%-32s %-18s %-32s %6s %8s", "FILE", "ENCODER", "PROGRESS", "SPEED", "ETA"))
+	room := height - len(lines) - len(view.Recent) - 3
+	for i, row := range view.Rows {
+		if i >= room && len(view.Rows) > room {
+			lines = append(lines, fmt.Sprintf("… %d more", len(view.Rows)-i))
+			break
+		}
+		status := "converting"
+		if row.Percent >= 0 {
+			status = fmt.Sprintf("%s %5.1f%%", progressBar(row.Percent, 20), row.Percent)
+		}
+		speed, eta := "-", "-"
+		if row.Speed > 0 {
+			speed = fmt.Sprintf("%.1fx", row.Speed)
+		}
+		if row.ETA > 0 {
+			eta = c.formatDuration(row.ETA)
+		}
+		lines = append(lines, fmt.Sprintf("%-32s %-18s %-32s %6s %8s",
+			truncateText(row.File, 32), truncateText(row.Encoder, 18), status, speed, eta))
+	}
+
+	if len(view.Recent) > 0 {
+		lines = append(lines, "", "Recent warnings and errors")
+		lines = append(lines, view.Recent...)
+	}
+
+	for i, line := range lines {
+		lines[i] = truncateText(line, width)
+	}
+	return lines
+}
+
+func progressBar(percent float64, width int) string {
+	filled := int(percent / 100 * float64(width))
+	if filled > width {
+		filled = width
+	}
+	if filled < 0 {
+		filled = 0
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// truncateText shortens text to width runes, marking the cut with an ellipsis.
+func truncateText(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string([]rune(text)[:width])
+	}
+	return string([]rune(text)[:width-1]) + "…"
+}
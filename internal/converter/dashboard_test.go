@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderDashboard(t *testing.T) {
+	c := &Converter{}
+	view := dashboardView{
+		Phase:       "videos",
+		Processed:   3,
+		Failed:      1,
+		Total:       8,
+		Elapsed:     4 * time.Minute,
+		ProcessedMB: 400,
+		SavedMB:     100,
+		Limit:       2,
+		Active:      2,
+		MaxJobs:     4,
+		Rows: []dashboardRowView{
+			{File: "vacation.mov", Encoder: "hevc_videotoolbox", Percent: 42.3, Speed: 1.2, ETA: 3 * time.Minute},
+			{File: "IMG_0001.MOV", Encoder: "video", Percent: -1},
+		},
+		Recent: []string{"✗ Failed to convert broken.mp4: video is corrupted"},
+	}
+
+	lines := c.renderDashboard(view, 120, 30)
+	screen := strings.Join(lines, "\n")
+	for _, expected := range []string{
+		"converting videos",
+		"4/8 (50.0%)  1 failed  ETA 4m0s",
+		"Saved 100.0 MB of 400.0 MB (25.0%)",
+		"Workers 2 active / adaptive limit 2 (max 4)",
+		"hevc_videotoolbox",
+		"42.3%",
+		"1.2x",
+		"converting",
+		"Failed to convert broken.mp4",
+	} {
+		if !strings.Contains(screen, expected) {
+			t.Errorf("dashboard is missing %q:\n%s", expected, screen)
+		}
+	}
+
+	// Narrow terminals cut lines instead of wrapping them
+	for _, line := range c.renderDashboard(view, 40, 30) {
+		if n := len([]rune(line)); n > 40 {
+			t.Errorf("line of %d runes exceeds the width: %q", n, line)
+		}
+	}
+
+	// Rows that do not fit are summarised
+	view.Rows = append(view.Rows, view.Rows...)
+	view.Rows = append(view.Rows, view.Rows...)
+	lines = c.renderDashboard(view, 120, 12)
+	if !strings.Contains(strings.Join(lines, "\n"), "more") {
+		t.Errorf("expected hidden rows to be summarised:\n%s", strings.Join(lines, "\n"))
+	}
+}
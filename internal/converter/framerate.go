@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// Sources above highSpeedFrameRate are slow-motion or high-speed footage. A
+// stream is variable frame rate when its average rate differs from its base
+// rate by more than vfrTolerance.
+const (
+	highSpeedFrameRate = 60.5
+	vfrTolerance       = 0.02
+)
+
+// slowMotionIntentTag is the QuickTime key iPhones set to 0 on slow-motion
+// clips: players then slow down the ramps stored in the timed metadata track.
+const slowMotionIntentTag = "com.apple.quicktime.full-frame-rate-playback-intent"
+
+// frameTiming describes how the frames of a source are timed.
+type frameTiming struct {
+	Rate     float64 // average frame rate
+	BaseRate float64 // lowest rate representing every timestamp (r_frame_rate)
+	Variable bool
+	// HighSpeed is set for footage shot above 60 fps
+	HighSpeed bool
+	// SlowMotion is set for iPhone slow-motion clips; RampTrack when their
+	// slow-motion ramps are stored in a timed metadata (mebx) track
+	SlowMotion bool
+	RampTrack  bool
+}
+
+// readFrameTiming reads the frame timing of the primary video stream.
+func readFrameTiming(probe *utils.MediaProbe) (frameTiming, bool) {
+	video := probe.VideoStream()
+	if video == nil {
+		return frameTiming{}, false
+	}
+
+	timing := frameTiming{
+		Rate:     video.FrameRate(),
+		BaseRate: utils.ParseRational(video.RFrameRate),
+	}
+	if timing.Rate <= 0 {
+		return frameTiming{}, false
+	}
+	// Field-coded streams report the field rate as their base rate
+	if timing.BaseRate > 0 && !video.Interlaced() {
+		timing.Variable = math.Abs(timing.BaseRate-timing.Rate)/timing.BaseRate > vfrTolerance
+	}
+	timing.HighSpeed = timing.Rate > highSpeedFrameRate
+
+	for _, stream := range probe.StreamsOfType("data") {
+		if stream.CodecTagString == "mebx" {
+			timing.RampTrack = true
+		}
+	}
+	intent := probe.Tag(slowMotionIntentTag)
+	timing.SlowMotion = timing.HighSpeed && (intent == "0" || (intent == "" && timing.RampTrack))
+	timing.RampTrack = timing.RampTrack && timing.SlowMotion
+	return timing, true
+}
+
+// describe returns a short label for logs.
+func (t frameTiming) describe() string {
+	var parts []string
+	switch {
+	case t.SlowMotion:
+		parts = append(parts, "slow motion")
+	case t.HighSpeed:
+		parts = append(parts, "high speed")
+	}
+	if t.Variable {
+		parts = append(parts, fmt.Sprintf("variable frame rate, base %s fps", formatFrameRate(t.BaseRate)))
+	}
+	return fmt.Sprintf("%s fps, %s", formatFrameRate(t.Rate), strings.Join(parts, ", "))
+}
+
+// frameRateSettings holds the arguments applying a frame timing decision.
+type frameRateSettings struct {
+	Filter string   // fps filter conforming to a constant rate
+	Args   []string // -fps_mode output option
+	// SlowMotionLost is set when conforming drops the extra frames of
+	// slow-motion footage
+	SlowMotionLost bool
+}
+
+// planFrameRate decides how source timestamps are carried into the output.
+// Passthrough keeps every frame with its own timestamp, so ffmpeg neither
+// duplicates frames of VFR screen recordings nor drops frames of 120/240 fps
+// footage. Conform converts to a constant rate with the fps filter.
+func planFrameRate(mode string, conform float64, timing frameTiming) (frameRateSettings, bool) {
+	switch mode {
+	case "conform":
+		settings := frameRateSettings{
+			Filter: "fps=" + formatFrameRate(conform),
+			Args:   []string{"-fps_mode", "cfr"},
+		}
+		settings.SlowMotionLost = timing.HighSpeed && timing.Rate > conform*(1+vfrTolerance)
+		return settings, true
+	case "passthrough":
+		return frameRateSettings{Args: []string{"-fps_mode", "passthrough"}}, true
+	default:
+		if !timing.Variable && !timing.HighSpeed {
+			return frameRateSettings{}, false
+		}
+		return frameRateSettings{Args: []string{"-fps_mode", "passthrough"}}, true
+	}
+}
+
+// formatFrameRate prints a rate without trailing zeros (30, 29.97, 239.76).
+func formatFrameRate(rate float64) string {
+	return strconv.FormatFloat(math.Round(rate*100)/100, 'f', -1, 64)
+}
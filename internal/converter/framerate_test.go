@@ -0,0 +1,113 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+func TestReadFrameTiming(t *testing.T) {
+	tests := []struct {
+		name     string
+		probe    *utils.MediaProbe
+		expected frameTiming
+	}{
+		{
+			name: "constant 30 fps",
+			probe: &utils.MediaProbe{Streams: []utils.ProbeStream{
+				{CodecType: "video", RFrameRate: "30/1", AvgFrameRate: "2997/100"},
+			}},
+			expected: frameTiming{Rate: 29.97, BaseRate: 30},
+		},
+		{
+			name: "screen recording",
+			probe: &utils.MediaProbe{Streams: []utils.ProbeStream{
+				{CodecType: "video", RFrameRate: "60/1", AvgFrameRate: "2371/100"},
+			}},
+			expected: frameTiming{Rate: 23.71, BaseRate: 60, Variable: true},
+		},
+		{
+			name: "interlaced field rate",
+			probe: &utils.MediaProbe{Streams: []utils.ProbeStream{
+				{CodecType: "video", RFrameRate: "50/1", AvgFrameRate: "25/1", FieldOrder: "tt"},
+			}},
+			expected: frameTiming{Rate: 25, BaseRate: 50},
+		},
+		{
+			name: "iPhone slow motion",
+			probe: &utils.MediaProbe{
+				Streams: []utils.ProbeStream{
+					{CodecType: "video", RFrameRate: "240/1", AvgFrameRate: "240/1"},
+					{CodecType: "data", CodecTagString: "mebx"},
+				},
+				Format: utils.ProbeFormat{Tags: map[string]string{slowMotionIntentTag: "0"}},
+			},
+			expected: frameTiming{Rate: 240, BaseRate: 240, HighSpeed: true, SlowMotion: true, RampTrack: true},
+		},
+		{
+			name: "high speed played at full rate",
+			probe: &utils.MediaProbe{
+				Streams: []utils.ProbeStream{
+					{CodecType: "video", RFrameRate: "120/1", AvgFrameRate: "120/1"},
+					{CodecType: "data", CodecTagString: "mebx"},
+				},
+				Format: utils.ProbeFormat{Tags: map[string]string{slowMotionIntentTag: "1"}},
+			},
+			expected: frameTiming{Rate: 120, BaseRate: 120, HighSpeed: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timing, ok := readFrameTiming(tt.probe)
+			if !ok {
+				t.Fatal("expected frame timing")
+			}
+			if timing != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, timing)
+			}
+		})
+	}
+}
+
+func TestPlanFrameRate(t *testing.T) {
+	constant := frameTiming{Rate: 30, BaseRate: 30}
+	slowMotion := frameTiming{Rate: 240, BaseRate: 240, HighSpeed: true, SlowMotion: true}
+	passthrough := []string{"-fps_mode", "passthrough"}
+
+	if _, apply := planFrameRate("auto", 30, constant); apply {
+		t.Error("constant frame rate sources should use the default timing")
+	}
+	if settings, apply := planFrameRate("auto", 30, slowMotion); !apply || !reflect.DeepEqual(settings.Args, passthrough) || settings.Filter != "" {
+		t.Errorf("high-speed sources should pass timestamps through, got %+v", settings)
+	}
+	if settings, _ := planFrameRate("passthrough", 30, constant); !reflect.DeepEqual(settings.Args, passthrough) {
+		t.Errorf("passthrough mode should apply to every source, got %+v", settings)
+	}
+
+	settings, _ := planFrameRate("conform", 29.97, slowMotion)
+	if settings.Filter != "fps=29.97" || !reflect.DeepEqual(settings.Args, []string{"-fps_mode", "cfr"}) || !settings.SlowMotionLost {
+		t.Errorf("unexpected conform settings for slow motion: %+v", settings)
+	}
+	if settings, _ := planFrameRate("conform", 30, constant); settings.SlowMotionLost {
+		t.Error("conforming a 30 fps source loses no slow motion")
+	}
+}
+
+func TestDiffVideoMetadataFrameRate(t *testing.T) {
+	source := videoMetadata{Tags: map[string]string{}, FrameRate: 240}
+	output := &utils.MediaProbe{Streams: []utils.ProbeStream{
+		{CodecType: "video", Width: 1920, Height: 1080, RFrameRate: "30/1", AvgFrameRate: "30/1"},
+	}}
+
+	lost := diffVideoMetadata(source, output)
+	if !reflect.DeepEqual(lost, []string{"frame rate (240 -> 30 fps)"}) {
+		t.Errorf("expected frame rate loss, got %v", lost)
+	}
+
+	output.Streams[0].AvgFrameRate = "240/1"
+	if lost := diffVideoMetadata(source, output); len(lost) != 0 {
+		t.Errorf("expected no loss, got %v", lost)
+	}
+}
@@ -0,0 +1,207 @@
+package converter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// tonemapFilter converts PQ/HLG frames to BT.709 SDR. Requires ffmpeg built
+// with zimg (zscale).
+const tonemapFilter = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709," +
+	"tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
+
+// hdrSettings holds the extra encoder arguments for an HDR source.
+type hdrSettings struct {
+	Source       utils.HDRInfo
+	Filters      []string
+	Args         []string
+	X265Params   []string
+	SVTAV1Params []string
+	Tonemapped   bool
+}
+
+// planHDR detects PQ/HLG sources and returns how they are encoded. It returns
+// false for SDR sources or when the source cannot be probed.
+func (c *Converter) planHDR(inputPath string, profile videoEncodingProfile) (hdrSettings, bool) {
+	info, err := utils.GetVideoHDRInfo(inputPath)
+	if err != nil || !info.HDR() {
+		return hdrSettings{}, false
+	}
+
+	filename := filepath.Base(inputPath)
+	settings := hdrSettings{Source: info}
+
+	tonemap := c.config.HDRMode == "tonemap"
+	if !tonemap && strings.Contains(profile.Codec, "264") {
+		c.logger.Warn(fmt.Sprintf("🌈 %s: H.264 output cannot carry HDR reliably, tone-mapping to SDR", filename))
+		tonemap = true
+	}
+
+	if tonemap {
+		settings.Tonemapped = true
+		settings.Filters = []string{tonemapFilter}
+		settings.Args = []string{
+			"-color_primaries", "bt709",
+			"-color_trc", "bt709",
+			"-colorspace", "bt709",
+			"-color_range", "tv",
+		}
+		return settings, true
+	}
+
+	if info.DolbyVisionProfile > 0 {
+		c.logger.Warn(fmt.Sprintf("🌈 %s: Dolby Vision profile %d metadata is dropped, the base layer HDR is kept", filename, info.DolbyVisionProfile))
+	}
+
+	primaries := info.Primaries
+	if primaries == "" || primaries == "unknown" {
+		primaries = "bt2020"
+	}
+	matrix := info.Matrix
+	if matrix == "" || matrix == "unknown" {
+		matrix = "bt2020nc"
+	}
+
+	switch {
+	case profile.Codec == "libx265":
+		settings.Args = append(settings.Args, "-pix_fmt", "yuv420p10le", "-profile:v", "main10")
+		settings.X265Params = x265HDRParams(info, primaries, matrix)
+	case profile.Codec == "libsvtav1":
+		settings.Args = append(settings.Args, "-pix_fmt", "yuv420p10le")
+		settings.SVTAV1Params = svtav1HDRParams(info)
+	case profile.UsingHardware:
+		if profile.Hardware.Codec == "h265" {
+			settings.Args = append(settings.Args, "-profile:v", "main10")
+		}
+		// Uploaded frames get their 10-bit format from the upload filter
+		if !profile.Hardware.Backend.Upload {
+			settings.Args = append(settings.Args, "-pix_fmt", "p010le")
+		}
+	default:
+		settings.Args = append(settings.Args, "-pix_fmt", "yuv420p10le")
+	}
+
+	// Only libx265 and libsvtav1 take the HDR10 static metadata
+	if settings.X265Params == nil && settings.SVTAV1Params == nil && (info.MasteringDisplay != "" || info.ContentLight != "") {
+		c.logger.Warn(fmt.Sprintf("🌈 %s: %s cannot carry the mastering display and content light metadata, it is dropped", filename, profile.Codec))
+	}
+
+	settings.Args = append(settings.Args,
+		"-color_primaries", primaries,
+		"-color_trc", info.Transfer,
+		"-colorspace", matrix,
+		"-color_range", "tv",
+	)
+	return settings, true
+}
+
+// x265HDRParams passes the colour signalling and HDR10 static metadata
+// through to the encoder's VUI and SEI messages.
+func x265HDRParams(info utils.HDRInfo, primaries, matrix string) []string {
+	params := []string{
+		"colorprim=" + primaries,
+		"transfer=" + info.Transfer,
+		"colormatrix=" + matrix,
+		"range=limited",
+		"repeat-headers=1",
+	}
+	if info.PQ() {
+		params = append(params, "hdr10=1", "hdr10-opt=1")
+	}
+	if info.MasteringDisplay != "" {
+		params = append(params, "master-display="+info.MasteringDisplay)
+	}
+	if info.ContentLight != "" {
+		params = append(params, "max-cll="+info.ContentLight)
+	}
+	return params
+}
+
+// svtav1HDRParams passes the HDR10 static metadata through to SVT-AV1, which
+// takes chromaticities and luminances as decimals rather than x265's units.
+func svtav1HDRParams(info utils.HDRInfo) []string {
+	var params []string
+	var gx, gy, bx, by, rx, ry, wx, wy, maxL, minL int64
+	if _, err := fmt.Sscanf(info.MasteringDisplay, "G(%d,%d)B(%d,%d)R(%d,%d)WP(%d,%d)L(%d,%d)",
+		&gx, &gy, &bx, &by, &rx, &ry, &wx, &wy, &maxL, &minL); err == nil {
+		chroma := func(v int64) float64 { return float64(v) / 50000 }
+		luminance := func(v int64) float64 { return float64(v) / 10000 }
+		params = append(params, fmt.Sprintf("mastering-display=G(%.4f,%.4f)B(%.4f,%.4f)R(%.4f,%.4f)WP(%.4f,%.4f)L(%.4f,%.4f)",
+			chroma(gx), chroma(gy), chroma(bx), chroma(by), chroma(rx), chroma(ry),
+			chroma(wx), chroma(wy), luminance(maxL), luminance(minL)))
+	}
+	if info.ContentLight != "" {
+		params = append(params, "content-light="+info.ContentLight)
+	}
+	if len(params) > 0 {
+		params = append([]string{"enable-hdr=1"}, params...)
+	}
+	return params
+}
+
+// describe returns a short label for logs.
+func (h hdrSettings) describe() string {
+	transfer := "HLG"
+	if h.Source.PQ() {
+		transfer = "PQ"
+	}
+	if h.Tonemapped {
+		return transfer + " → SDR"
+	}
+	return transfer + " 10-bit"
+}
+
+// photoOutputDepth returns the AVIF bit depth for high bit depth HEIF/AVIF
+// sources (10 or 12), or 0 to keep ImageMagick's 8-bit default. Gain maps
+// cannot be carried over and are reported.
+func (c *Converter) photoOutputDepth(inputPath, sourcePath string, plan conversionPlan) int {
+	if plan.Format != "avif" || !utils.HasExtension(inputPath, []string{"heic", "heif", "avif"}) {
+		return 0
+	}
+
+	if hasGainMap, err := utils.HasGainMap(inputPath); err == nil && hasGainMap {
+		c.logger.Warn(fmt.Sprintf("🌈 %s: HDR gain map is not preserved, the output shows the SDR base image", filepath.Base(inputPath)))
+	}
+
+	attrs, err := utils.GetImageAttributes(sourcePath)
+	if err != nil || attrs.BitDepth <= 8 {
+		return 0
+	}
+	if attrs.BitDepth > 10 {
+		return 12
+	}
+	return 10
+}
+
+// photoHDRCICP returns the nclx triplet for the AVIF output of a PQ/HLG
+// HEIF/AVIF source, or "" for SDR sources and other outputs. The pixels are
+// carried over unchanged, so the output must keep the source transfer.
+func (c *Converter) photoHDRCICP(inputPath string, plan conversionPlan) string {
+	if plan.Format != "avif" || !utils.HasExtension(inputPath, []string{"heic", "heif", "avif"}) {
+		return ""
+	}
+	source, err := utils.GetImageCICP(inputPath)
+	if err != nil || !source.HDR() {
+		return ""
+	}
+	return hdrCICP(source, plan.Lossless)
+}
+
+// hdrCICP completes the source triplet, defaulting unspecified primaries and
+// matrix to BT.2020 (9/16/9 for PQ, 9/18/9 for HLG).
+func hdrCICP(source utils.ImageCICP, lossless bool) string {
+	cicp := source
+	if cicp.Primaries == 0 || cicp.Primaries == 2 {
+		cicp.Primaries = 9
+	}
+	if cicp.Matrix == 2 {
+		cicp.Matrix = 9
+	}
+	if lossless {
+		cicp.Matrix = 0 // Lossless AVIF requires the identity matrix
+	}
+	return cicp.String()
+}
@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+func TestX265HDRParamsCarryStaticMetadata(t *testing.T) {
+	pq := utils.HDRInfo{
+		Transfer:         "smpte2084",
+		MasteringDisplay: "G(13250,34500)B(7500,3000)R(34000,16000)WP(15635,16450)L(10000000,50)",
+		ContentLight:     "1000,400",
+	}
+	params := strings.Join(x265HDRParams(pq, "bt2020", "bt2020nc"), ":")
+	for _, want := range []string{"transfer=smpte2084", "hdr10=1", "master-display=G(13250,34500)", "max-cll=1000,400"} {
+		if !strings.Contains(params, want) {
+			t.Fatalf("expected %q in x265 params, got %q", want, params)
+		}
+	}
+
+	// HLG has no HDR10 SEI messages
+	params = strings.Join(x265HDRParams(utils.HDRInfo{Transfer: "arib-std-b67"}, "bt2020", "bt2020nc"), ":")
+	if strings.Contains(params, "hdr10") || !strings.Contains(params, "transfer=arib-std-b67") {
+		t.Fatalf("unexpected HLG x265 params: %q", params)
+	}
+}
+
+func TestHDRCICPKeepsPhotoTransfer(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   utils.ImageCICP
+		lossless bool
+		want     string
+	}{
+		{"PQ", utils.ImageCICP{Primaries: 9, Transfer: utils.TransferPQ, Matrix: 9}, false, "9/16/9"},
+		{"HLG", utils.ImageCICP{Primaries: 9, Transfer: utils.TransferHLG, Matrix: 9}, false, "9/18/9"},
+		{"unspecified primaries and matrix", utils.ImageCICP{Primaries: 2, Transfer: utils.TransferPQ, Matrix: 2}, false, "9/16/9"},
+		{"Display P3 PQ", utils.ImageCICP{Primaries: 12, Transfer: utils.TransferPQ, Matrix: 6}, false, "12/16/6"},
+		{"lossless", utils.ImageCICP{Primaries: 9, Transfer: utils.TransferHLG, Matrix: 9}, true, "9/18/0"},
+	}
+	for _, tt := range tests {
+		if !tt.source.HDR() {
+			t.Errorf("%s: expected an HDR source", tt.name)
+		}
+		if got := hdrCICP(tt.source, tt.lossless); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if (utils.ImageCICP{Primaries: 1, Transfer: 13, Matrix: 6}).HDR() {
+		t.Error("sRGB transfer is not HDR")
+	}
+}
+
+func TestSVTAV1HDRParamsMergeWithFilmGrain(t *testing.T) {
+	pq := utils.HDRInfo{
+		Transfer:         "smpte2084",
+		MasteringDisplay: "G(13250,34500)B(7500,3000)R(34000,16000)WP(15635,16450)L(10000000,50)",
+		ContentLight:     "1000,400",
+	}
+	args := appendSVTAV1Params(av1EncoderArgs("libsvtav1", "medium", 8), svtav1HDRParams(pq))
+	want := "-preset 8 -svtav1-params film-grain=8:film-grain-denoise=0:enable-hdr=1:" +
+		"mastering-display=G(0.2650,0.6900)B(0.1500,0.0600)R(0.6800,0.3200)WP(0.3127,0.3290)L(1000.0000,0.0050):" +
+		"content-light=1000,400"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Without film grain the parameters get their own option
+	args = appendSVTAV1Params(av1EncoderArgs("libsvtav1", "medium", 0), svtav1HDRParams(pq))
+	if got := strings.Join(args, " "); !strings.HasPrefix(got, "-preset 8 -svtav1-params enable-hdr=1:mastering-display=") {
+		t.Errorf("unexpected SVT-AV1 arguments: %q", got)
+	}
+
+	// HLG without static metadata adds nothing
+	if params := svtav1HDRParams(utils.HDRInfo{Transfer: "arib-std-b67"}); params != nil {
+		t.Errorf("expected no parameters, got %v", params)
+	}
+}
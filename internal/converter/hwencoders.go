@@ -0,0 +1,189 @@
+package converter
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// encoderBackend describes a family of hardware encoders: how it is detected,
+// how frames reach the device and how a CRF maps to its rate control.
+type encoderBackend struct {
+	Name  string
+	Label string
+
+	// GOOS restricts the backend to one platform, empty allows any
+	GOOS string
+	// DeviceGlob matches the device nodes the backend needs, empty when none
+	DeviceGlob string
+
+	// Encoders maps a target codec (h265, h264, av1) to its ffmpeg encoder
+	Encoders   map[string]string
+	OutputTags map[string]string
+
+	// HwAccel returns the device and decoding arguments placed before -i
+	HwAccel func(device string) []string
+	// Upload is set when frames must be uploaded to hardware surfaces
+	Upload bool
+	// Quality maps a CRF to constant-quality arguments. Backends without one
+	// are driven by a bitrate estimated from the source.
+	Quality func(codec string, crf int) []string
+}
+
+// encoderBackends lists the hardware backends in order of preference.
+var encoderBackends = []encoderBackend{
+	{
+		Name:       "videotoolbox",
+		Label:      "VideoToolbox",
+		GOOS:       "darwin",
+		Encoders:   map[string]string{"h265": "hevc_videotoolbox", "h264": "h264_videotoolbox"},
+		OutputTags: map[string]string{"h265": "hvc1"},
+		HwAccel: func(string) []string {
+			return []string{"-hwaccel", "videotoolbox"}
+		},
+	},
+	{
+		Name:       "nvenc",
+		Label:      "NVENC",
+		Encoders:   map[string]string{"h265": "hevc_nvenc", "h264": "h264_nvenc", "av1": "av1_nvenc"},
+		OutputTags: map[string]string{"h265": "hvc1"},
+		HwAccel: func(string) []string {
+			return []string{"-hwaccel", "cuda"}
+		},
+		Quality: func(_ string, crf int) []string {
+			return []string{"-preset", "p5", "-rc", "vbr", "-cq", strconv.Itoa(crf), "-b:v", "0"}
+		},
+	},
+	{
+		Name:       "qsv",
+		Label:      "Intel Quick Sync",
+		GOOS:       "linux",
+		DeviceGlob: "/dev/dri/renderD*",
+		Encoders:   map[string]string{"h265": "hevc_qsv", "h264": "h264_qsv", "av1": "av1_qsv"},
+		OutputTags: map[string]string{"h265": "hvc1"},
+		HwAccel: func(device string) []string {
+			return []string{"-qsv_device", device}
+		},
+		Quality: func(_ string, crf int) []string {
+			return []string{"-preset", "medium", "-global_quality", strconv.Itoa(crf)}
+		},
+	},
+	{
+		Name:       "vaapi",
+		Label:      "VAAPI",
+		GOOS:       "linux",
+		DeviceGlob: "/dev/dri/renderD*",
+		Encoders:   map[string]string{"h265": "hevc_vaapi", "h264": "h264_vaapi", "av1": "av1_vaapi"},
+		OutputTags: map[string]string{"h265": "hvc1"},
+		HwAccel: func(device string) []string {
+			return []string{"-vaapi_device", device}
+		},
+		Upload: true,
+		Quality: func(codec string, crf int) []string {
+			qp := crf
+			if codec == "av1" {
+				qp = rav1eQuantizer(crf) // AV1 quantizers use a 0-255 scale
+			}
+			return []string{"-rc_mode", "CQP", "-qp", strconv.Itoa(qp)}
+		},
+	},
+}
+
+// hardwareEncoder is a detected, working encoder of a backend.
+type hardwareEncoder struct {
+	Backend encoderBackend
+	Codec   string
+	Encoder string
+	Device  string
+}
+
+// describe returns a short label for logs.
+func (h hardwareEncoder) describe() string {
+	return fmt.Sprintf("%s %s", h.Backend.Label, h.Encoder)
+}
+
+// uploadFilter returns the filter moving frames to hardware surfaces, or an
+// empty string when the backend reads system memory.
+func (h hardwareEncoder) uploadFilter(tenBit bool) string {
+	if !h.Backend.Upload {
+		return ""
+	}
+	if tenBit {
+		return "format=p010,hwupload"
+	}
+	return "format=nv12,hwupload"
+}
+
+// detectHardwareEncoders returns the working encoders of each target codec,
+// in backend order. A backend is tried when it matches the platform, its
+// device node exists and ffmpeg lists its encoder; a one-frame test encode
+// then confirms the hardware is usable.
+func detectHardwareEncoders(ffmpegCmd []string, backends []encoderBackend, goos string, available map[string]bool) map[string][]hardwareEncoder {
+	detected := make(map[string][]hardwareEncoder)
+	for _, backend := range backends {
+		if backend.GOOS != "" && backend.GOOS != goos {
+			continue
+		}
+
+		device := ""
+		if backend.DeviceGlob != "" {
+			matches, _ := filepath.Glob(backend.DeviceGlob)
+			if len(matches) == 0 {
+				continue
+			}
+			device = matches[0]
+		}
+
+		for _, codec := range []string{"h265", "h264", "av1"} {
+			encoder, ok := backend.Encoders[codec]
+			if !ok || !available[encoder] {
+				continue
+			}
+			hw := hardwareEncoder{Backend: backend, Codec: codec, Encoder: encoder, Device: device}
+			if err := utils.ProbeFFmpegEncoder(ffmpegCmd, backend.HwAccel(device), encoder, hw.uploadFilter(false)); err != nil {
+				continue
+			}
+			detected[codec] = append(detected[codec], hw)
+		}
+	}
+	return detected
+}
+
+// hardwareEncoders detects the hardware encoders once and logs the result.
+func (c *Converter) hardwareEncoders() map[string][]hardwareEncoder {
+	c.accelOnce.Do(func() {
+		if !c.config.VideoAcceleration {
+			c.logger.Info("📹 Hardware acceleration disabled in config")
+			return
+		}
+		c.hwEncoders = detectHardwareEncoders(c.ffmpegCommand, encoderBackends, runtime.GOOS, c.availableEncoders())
+
+		var names []string
+		for _, codec := range []string{"h265", "h264", "av1"} {
+			for _, hw := range c.hwEncoders[codec] {
+				names = append(names, hw.describe())
+			}
+		}
+		if len(names) == 0 {
+			c.logger.Info("📹 No hardware video encoder available - using software encoding")
+			return
+		}
+		c.logger.Info(fmt.Sprintf("📹 Hardware encoders available: %s", strings.Join(names, ", ")))
+	})
+	return c.hwEncoders
+}
+
+// hardwareEncoderFor returns the preferred working encoder for a target
+// codec, restricted to the configured backend unless it is "auto".
+func (c *Converter) hardwareEncoderFor(codec string) (hardwareEncoder, bool) {
+	for _, hw := range c.hardwareEncoders()[codec] {
+		if c.config.VideoHWBackend == "auto" || c.config.VideoHWBackend == hw.Backend.Name {
+			return hw, true
+		}
+	}
+	return hardwareEncoder{}, false
+}
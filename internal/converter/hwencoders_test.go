@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/config"
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// fakeFFmpeg lists hardware encoders of every backend but only lets the NVENC
+// HEVC encoder and the VAAPI H.264 encoder (with its device and upload filter)
+// pass the test encode.
+const fakeFFmpeg = `#!/bin/sh
+case "$*" in
+*-encoders*)
+	echo "Encoders:"
+	echo " ------"
+	for e in hevc_videotoolbox hevc_nvenc h264_nvenc hevc_vaapi h264_vaapi libx265; do
+		echo " V....D $e   fake encoder"
+	done
+	;;
+*"-c:v hevc_nvenc"*) exit 0 ;;
+*"-vaapi_device $FAKE_DEVICE "*"format=nv12,hwupload -c:v h264_vaapi"*) exit 0 ;;
+*) echo "no device" >&2; exit 1 ;;
+esac
+`
+
+func TestDetectHardwareEncodersWithFakeFFmpeg(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeFFmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	device := filepath.Join(dir, "renderD128")
+	if err := os.WriteFile(device, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAKE_DEVICE", device)
+
+	backends := append([]encoderBackend{}, encoderBackends...)
+	for i := range backends {
+		if backends[i].DeviceGlob != "" {
+			backends[i].DeviceGlob = filepath.Join(dir, "renderD*")
+		}
+	}
+
+	available, err := utils.ListFFmpegEncoders([]string{ffmpeg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detected := detectHardwareEncoders([]string{ffmpeg}, backends, "linux", available)
+
+	if got := encoderNames(detected["h265"]); got != "hevc_nvenc" {
+		t.Errorf("h265: got %q, want hevc_nvenc (VideoToolbox is darwin only, VAAPI HEVC fails its probe)", got)
+	}
+	if got := encoderNames(detected["h264"]); got != "h264_vaapi" {
+		t.Errorf("h264: got %q, want h264_vaapi", got)
+	}
+	if hw := detected["h264"]; len(hw) == 1 && hw[0].Device != device {
+		t.Errorf("VAAPI device: got %q, want %q", hw[0].Device, device)
+	}
+}
+
+func TestHardwareProfileMapsCRF(t *testing.T) {
+	c := &Converter{config: &config.Config{VideoMode: "cq", VideoMaxBitrate: "8M"}}
+	nvenc := hardwareEncoder{Backend: backendNamed(t, "nvenc"), Codec: "h265", Encoder: "hevc_nvenc"}
+
+	profile := c.hardwareProfile(nvenc, 26, "clip.mov")
+	if got := strings.Join(profile.Args, " "); got != "-preset p5 -rc vbr -cq 26 -b:v 0 -maxrate 8000k -bufsize 16000k" {
+		t.Errorf("unexpected NVENC arguments: %q", got)
+	}
+	if strings.Join(profile.HwAccelArgs, " ") != "-hwaccel cuda" || profile.OutputTag != "hvc1" || !profile.UsingHardware {
+		t.Errorf("unexpected NVENC profile: %+v", profile)
+	}
+
+	vaapi := hardwareEncoder{Backend: backendNamed(t, "vaapi"), Codec: "av1", Encoder: "av1_vaapi", Device: "/dev/dri/renderD128"}
+	profile = c.hardwareProfile(vaapi, 63, "clip.mov")
+	if got := strings.Join(profile.Args, " "); !strings.HasPrefix(got, "-rc_mode CQP -qp 255") {
+		t.Errorf("unexpected VAAPI AV1 arguments: %q", got)
+	}
+	if vaapi.uploadFilter(true) != "format=p010,hwupload" {
+		t.Errorf("unexpected upload filter: %q", vaapi.uploadFilter(true))
+	}
+}
+
+func encoderNames(encoders []hardwareEncoder) string {
+	var names []string
+	for _, hw := range encoders {
+		names = append(names, hw.Encoder)
+	}
+	return strings.Join(names, ",")
+}
+
+func backendNamed(t *testing.T, name string) encoderBackend {
+	for _, backend := range encoderBackends {
+		if backend.Name == name {
+			return backend
+		}
+	}
+	t.Fatalf("no %s backend", name)
+	return encoderBackend{}
+}
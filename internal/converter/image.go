@@ -9,21 +9,60 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kevindurb/media-converter/internal/config"
 	"github.com/kevindurb/media-converter/internal/utils"
 )
 
 func (c *Converter) convertFile(inputPath, fileType string) error {
-	switch fileType {
+	job, chaptered := c.chapters[inputPath]
+	if chaptered && job.Part == 0 {
+		return c.convertChapterGroup(job, fileType)
+	}
+
+	// Evaluate routing rules before dispatching to a pipeline
+	plan := c.planConversion(inputPath, fileType)
+	if chaptered {
+		plan.Chapters = &job
+	}
+
+	switch plan.Action {
+	case config.ActionSkip:
+		c.logger.Info(fmt.Sprintf("⏭️  %s skipped (%s)", filepath.Base(inputPath), plan.RuleName))
+		c.stats.mu.Lock()
+		c.stats.ruleSkippedFiles++
+		c.stats.mu.Unlock()
+		c.metrics.fileEvent(eventSkipped, fileType)
+		return nil
+	case config.ActionCopy:
+		return c.copyOriginal(inputPath, fileType, plan.RuleName)
+	}
+
+	// Detect animated sources before choosing a pipeline
+	if fileType == "photo" && utils.HasExtension(inputPath, animatableFormats) {
+		c.detectAnimation(inputPath, &plan)
+	}
+
+	// Probe already-efficient sources and bypass transcoding when the gain is small
+	if c.config.CopyThrough.Enabled {
+		if decision, ok := c.evaluatePassthrough(inputPath, plan); ok {
+			if decision.Remux {
+				return c.remuxOriginal(inputPath, decision.Reason)
+			}
+			return c.copyOriginal(inputPath, fileType, decision.Reason)
+		}
+	}
+
+	switch plan.MediaType {
 	case "photo":
-		return c.convertImage(inputPath)
+		return c.convertImage(inputPath, plan)
 	case "video":
-		return c.convertVideo(inputPath)
+		return c.convertVideo(inputPath, plan)
 	default:
 		return fmt.Errorf("unknown file type: %s", fileType)
 	}
 }
 
-func (c *Converter) convertImage(inputPath string) error {
+func (c *Converter) convertImage(inputPath string, plan conversionPlan) error {
 	filename := filepath.Base(inputPath)
 	name := strings.TrimSuffix(filename, filepath.Ext(filename))
 
@@ -42,7 +81,7 @@ func (c *Converter) convertImage(inputPath string) error {
 	}
 
 	// Generate base filename and check if already converted
-	baseName := utils.CleanFilename(name, c.config.PhotoFormat, fileDate, 1)
+	baseName := utils.CleanFilename(name, plan.Format, fileDate, 1)
 	baseOutputPath := filepath.Join(destPath, baseName)
 
 	// Check if file already exists and is valid (idempotency check with integrity verification)
@@ -53,6 +92,7 @@ func (c *Converter) convertImage(inputPath string) error {
 			c.stats.mu.Lock()
 			c.stats.skippedFiles++
 			c.stats.mu.Unlock()
+			c.metrics.fileEvent(eventSkipped, "photo")
 			return nil
 		} else {
 			// File is corrupted, remove it and proceed with conversion
@@ -61,9 +101,20 @@ func (c *Converter) convertImage(inputPath string) error {
 			c.stats.mu.Lock()
 			c.stats.recoveredFiles++
 			c.stats.mu.Unlock()
+			c.metrics.fileEvent(eventRecovered, "photo")
 		}
 	}
 
+	// A previous run may have kept the original because its encode was not smaller
+	if keptName, ok := c.findKeptOriginal(inputPath, destPath, name, fileDate); ok {
+		c.logger.Info(fmt.Sprintf("📷 %s -> %s (original already kept, skipping)", filename, keptName))
+		c.stats.mu.Lock()
+		c.stats.skippedFiles++
+		c.stats.mu.Unlock()
+		c.metrics.fileEvent(eventSkipped, "photo")
+		return nil
+	}
+
 	// Use the base name for conversion
 	cleanName := baseName
 	outputPath := baseOutputPath
@@ -71,7 +122,13 @@ func (c *Converter) convertImage(inputPath string) error {
 
 	// Dry run mode
 	if c.config.DryRun {
-		c.logger.Info(fmt.Sprintf("[DRY-RUN] Would convert: %s → %s", filename, cleanName))
+		if plan.Animation != nil && !plan.Flatten {
+			c.logger.Info(fmt.Sprintf("[DRY-RUN] Would convert: %s → %s (animated, %d frames)", filename, cleanName, plan.Animation.Frames))
+		} else if isRawSource(inputPath) {
+			c.logger.Info(fmt.Sprintf("[DRY-RUN] Would convert: %s → %s (RAW via %s)", filename, cleanName, c.config.Raw.Backend))
+		} else {
+			c.logger.Info(fmt.Sprintf("[DRY-RUN] Would convert: %s → %s", filename, cleanName))
+		}
 		return nil
 	}
 
@@ -80,7 +137,11 @@ func (c *Converter) convertImage(inputPath string) error {
 	fileSizeMB := float64(fileInfo.Size()) / (1024 * 1024)
 
 	// Show initial progress
-	c.logger.Info(fmt.Sprintf("📷 %s (%.1f MB) -> %s", filename, fileSizeMB, c.config.PhotoFormat))
+	if plan.RuleName != "" {
+		c.logger.Info(fmt.Sprintf("📷 %s (%.1f MB) -> %s (%s)", filename, fileSizeMB, plan.Format, plan.RuleName))
+	} else {
+		c.logger.Info(fmt.Sprintf("📷 %s (%.1f MB) -> %s", filename, fileSizeMB, plan.Format))
+	}
 
 	// Create processing marker
 	if err := c.security.CreateProcessingMarker(outputPath); err != nil {
@@ -97,30 +158,88 @@ func (c *Converter) convertImage(inputPath string) error {
 
 	// Convert to temporary file with timeout
 	startTime := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), c.config.ConversionTimeoutPhoto)
+	ctx, cancel := c.encodeContext(context.Background(), inputPath, c.config.ConversionTimeoutPhoto)
 	defer cancel()
 
 	var cmd *exec.Cmd
-	quality := c.config.PhotoQualityAVIF
-	if c.config.PhotoFormat == "webp" {
-		quality = c.config.PhotoQualityWebP
+
+	// RAW sources are developed by the configured backend first
+	sourcePath := inputPath
+	var rawMethod string
+	if isRawSource(inputPath) {
+		sourcePath, rawMethod, err = c.developRaw(ctx, inputPath, outputPath)
+		if sourcePath != inputPath {
+			defer os.Remove(sourcePath)
+		}
+		if err != nil {
+			return fmt.Errorf("RAW development failed: %w", err)
+		}
 	}
 
-	// Direct conversion for all image formats (including RAW)
 	// Preserve EXIF metadata during conversion to maintain original dates
-	cmd = exec.CommandContext(ctx, "magick", inputPath,
-		"-auto-orient",
-		"-quality", fmt.Sprintf("%d", quality),
+	var magickArgs []string
+	switch {
+	case plan.Flatten:
+		magickArgs = append(magickArgs, sourcePath+"[0]")
+	case plan.Animation != nil:
+		// Coalesce so every frame is complete before re-encoding
+		magickArgs = append(magickArgs, sourcePath, "-coalesce")
+	default:
+		magickArgs = append(magickArgs, sourcePath)
+	}
+	magickArgs = append(magickArgs, "-auto-orient")
+
+	// Embed the source ICC profile or convert to the configured target
+	colorOps, expectedProfile := c.colorArgs(sourcePath)
+	magickArgs = append(magickArgs, colorOps...)
+
+	// High bit depth HEIF sources keep 10/12-bit precision in AVIF
+	depth := c.photoOutputDepth(inputPath, sourcePath, plan)
+
+	// PQ/HLG sources keep their transfer in the nclx tags
+	hdrTags := c.photoHDRCICP(inputPath, plan)
+	if hdrTags != "" {
+		depth = max(depth, 10)
+		if !c.color.avifenc {
+			c.logger.Warn(fmt.Sprintf("🌈 %s: HDR photo needs avifenc to keep its %s colour tags", filename, hdrTags))
+		}
+	}
+
+	magickArgs = append(magickArgs,
+		"-quality", fmt.Sprintf("%d", plan.Quality),
 		"-define", "heic:preserve-orientation=true",
 		"-define", "avif:preserve-exif=true", // Preserve EXIF for AVIF
 		"-define", "webp:preserve-exif=true", // Preserve EXIF for WebP
-		fmt.Sprintf("%s:%s", c.config.PhotoFormat, tempPath))
+	)
+	if plan.Lossless {
+		magickArgs = append(magickArgs,
+			"-define", "webp:lossless=true",
+			"-define", "heic:lossless=true")
+	}
+
+	// Still AVIFs go through avifenc so the nclx colour tags match the profile
+	intermediatePath := ""
+	if plan.Format == "avif" && c.color.avifenc && (plan.Animation == nil || plan.Flatten) {
+		intermediatePath = outputPath + ".color.png"
+		defer os.Remove(intermediatePath)
+		if depth > 0 {
+			magickArgs = append(magickArgs, "-depth", "16")
+		}
+		magickArgs = append(magickArgs, "PNG:"+intermediatePath)
+	} else {
+		if depth > 0 {
+			magickArgs = append(magickArgs, "-depth", fmt.Sprintf("%d", depth))
+		}
+		magickArgs = append(magickArgs, fmt.Sprintf("%s:%s", plan.Format, tempPath))
+	}
+
+	cmd = exec.CommandContext(ctx, "magick", magickArgs...)
 
 	// Capture stderr for detailed error information
 	var stderrBuf strings.Builder
 	cmd.Stderr = &stderrBuf
 
-	if err := cmd.Run(); err != nil {
+	if err := c.runProcess(inputPath, cmd); err != nil {
 		stderrOutput := stderrBuf.String()
 		if stderrOutput != "" {
 			return fmt.Errorf("conversion failed: %w - ImageMagick Error: %s", err, strings.TrimSpace(stderrOutput))
@@ -128,13 +247,48 @@ func (c *Converter) convertImage(inputPath string) error {
 		return fmt.Errorf("conversion failed: %w", err)
 	}
 
+	if intermediatePath != "" {
+		cicp := hdrTags
+		if cicp == "" {
+			cicp = cicpFor(expectedProfile, plan.Lossless)
+		}
+		if err := c.encodeAVIFWithCICP(ctx, inputPath, intermediatePath, tempPath, plan, cicp, depth); err != nil {
+			return fmt.Errorf("conversion failed: %w", err)
+		}
+	}
+
 	conversionTime := time.Since(startTime)
 
 	// Verify temporary file integrity
-	if err := c.security.VerifyOutputFile(inputPath, tempPath, "photo", c.config.PhotoFormat); err != nil {
+	if err := c.security.VerifyOutputFile(inputPath, tempPath, "photo", plan.Format); err != nil {
 		return fmt.Errorf("output verification failed: %w", err)
 	}
 
+	// The output must still carry the expected colour space and HDR transfer
+	if err := c.security.VerifyColorProfile(tempPath, expectedProfile, hdrTags); err != nil {
+		return fmt.Errorf("colour verification failed: %w", err)
+	}
+
+	// Animated sources must keep every frame and their timing
+	if plan.Animation != nil && !plan.Flatten {
+		if err := c.security.VerifyAnimation(tempPath, "photo", *plan.Animation); err != nil {
+			if plan.Format == "avif" {
+				c.logger.Warn(fmt.Sprintf("🎞️  %s: animated AVIF not supported by this ImageMagick build (%v), falling back to animated WebP", filename, err))
+				os.Remove(tempPath)
+				fallback := plan
+				fallback.Format = "webp"
+				fallback.Quality = c.photoQuality("webp")
+				return c.convertImage(inputPath, fallback)
+			}
+			return fmt.Errorf("animation verification failed: %w", err)
+		}
+	}
+
+	// Keep the original when the encode does not save enough space
+	if savings, ok := c.meetsMinimumSavings(inputPath, tempPath); !ok {
+		return c.keepOriginalInstead(inputPath, tempPath, "photo", savings)
+	}
+
 	// Atomic move: rename temp file to final destination
 	if err := os.Rename(tempPath, outputPath); err != nil {
 		return fmt.Errorf("failed to finalize conversion: %w", err)
@@ -152,10 +306,18 @@ func (c *Converter) convertImage(inputPath string) error {
 	newFileSizeMB := float64(newInfo.Size()) / (1024 * 1024)
 	logEntry := fmt.Sprintf("✅ %s -> %s | -%d%% (%.1f->%.1f MB) | %v",
 		filename, cleanName, reduction, fileSizeMB, newFileSizeMB, c.formatDuration(conversionTime))
+	if rawMethod != "" {
+		logEntry += fmt.Sprintf(" | RAW: %s", rawMethod)
+	}
 	c.logger.Success(logEntry)
 
 	// Update size statistics
-	c.updateSizeStats(fileSizeMB, newFileSizeMB)
+	c.updateSizeStats(inputPath, "photo", plan.Format, fileSizeMB, newFileSizeMB)
+	if rawMethod != "" {
+		c.stats.mu.Lock()
+		c.stats.rawMethods[rawMethod]++
+		c.stats.mu.Unlock()
+	}
 
 	// Safe deletion if requested
 	if !c.config.KeepOriginals {
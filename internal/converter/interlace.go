@@ -0,0 +1,156 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// Frames analysed by idet, starting idetSampleOffset into the video (past
+// fades and camera start-up).
+const (
+	idetSampleFrames = 500
+	idetSampleOffset = 0.1
+	idetMaxOffset    = 60 * time.Second
+	idetTimeout      = 2 * time.Minute
+)
+
+var idetMultiFrameRegex = regexp.MustCompile(`Multi frame detection:\s*TFF:\s*(\d+)\s*BFF:\s*(\d+)\s*Progressive:\s*(\d+)`)
+
+// idetResult counts the frames idet classified in a sample.
+type idetResult struct {
+	TFF         int
+	BFF         int
+	Progressive int
+}
+
+// Interlaced reports whether most classified frames are interlaced.
+func (r idetResult) Interlaced() bool {
+	return r.TFF+r.BFF > r.Progressive
+}
+
+// Parity returns the dominant field order for the deinterlacing filter.
+func (r idetResult) Parity() string {
+	if r.BFF > r.TFF {
+		return "bff"
+	}
+	return "tff"
+}
+
+// parseIdetOutput reads the multi-frame summary idet prints on exit.
+func parseIdetOutput(output string) (idetResult, bool) {
+	match := idetMultiFrameRegex.FindStringSubmatch(output)
+	if len(match) < 4 {
+		return idetResult{}, false
+	}
+	tff, _ := strconv.Atoi(match[1])
+	bff, _ := strconv.Atoi(match[2])
+	progressive, _ := strconv.Atoi(match[3])
+	return idetResult{TFF: tff, BFF: bff, Progressive: progressive}, true
+}
+
+// deinterlaceSettings describes how an interlaced source is deinterlaced.
+type deinterlaceSettings struct {
+	Filter string
+	Reason string
+}
+
+// deinterlaceFilter builds the bwdif/yadif filter. Field rate outputs one
+// frame per field, frame rate one frame per interlaced frame.
+func deinterlaceFilter(filter, rate, parity string) string {
+	mode := "send_frame"
+	if rate == "field" {
+		mode = "send_field"
+	}
+	return fmt.Sprintf("%s=mode=%s:parity=%s:deint=all", filter, mode, parity)
+}
+
+// planDeinterlace detects interlaced content from the ffprobe field order,
+// confirmed or replaced by an idet sample depending on the mode. It returns
+// false for progressive sources.
+func (c *Converter) planDeinterlace(queued, inputPath string, video *utils.ProbeStream) (deinterlaceSettings, bool) {
+	cfg := c.config.Deinterlace
+	if cfg.Mode == "off" || video == nil {
+		return deinterlaceSettings{}, false
+	}
+
+	filename := filepath.Base(inputPath)
+	fieldOrder := video.FieldOrder
+	if fieldOrder == "" {
+		fieldOrder = "unknown"
+	}
+
+	// The field order is trusted in auto mode when ffprobe states it
+	if cfg.Mode == "auto" && fieldOrder != "unknown" {
+		if !video.Interlaced() {
+			return deinterlaceSettings{}, false
+		}
+		return deinterlaceSettings{
+			Filter: deinterlaceFilter(cfg.Filter, cfg.Rate, "auto"),
+			Reason: "field order " + fieldOrder,
+		}, true
+	}
+
+	result, err := c.runIdet(queued, inputPath)
+	if err != nil {
+		c.logger.Warn(fmt.Sprintf("🎞️  %s: interlace analysis failed (%v), using field order %s", filename, err, fieldOrder))
+		if !video.Interlaced() {
+			return deinterlaceSettings{}, false
+		}
+		return deinterlaceSettings{
+			Filter: deinterlaceFilter(cfg.Filter, cfg.Rate, "auto"),
+			Reason: "field order " + fieldOrder,
+		}, true
+	}
+
+	summary := fmt.Sprintf("idet TFF %d, BFF %d, progressive %d; field order %s", result.TFF, result.BFF, result.Progressive, fieldOrder)
+	if !result.Interlaced() {
+		if video.Interlaced() {
+			// Progressive content in interlaced fields (PsF) needs no filter
+			c.logger.Info(fmt.Sprintf("🎞️  %s: flagged interlaced but progressive content (%s), not deinterlacing", filename, summary))
+		}
+		return deinterlaceSettings{}, false
+	}
+	return deinterlaceSettings{
+		Filter: deinterlaceFilter(cfg.Filter, cfg.Rate, result.Parity()),
+		Reason: summary,
+	}, true
+}
+
+// runIdet classifies a sample of frames with the idet filter.
+func (c *Converter) runIdet(queued, inputPath string) (idetResult, error) {
+	var offset time.Duration
+	if duration, err := utils.GetVideoDuration(inputPath); err == nil {
+		offset = time.Duration(float64(duration) * idetSampleOffset)
+		if offset > idetMaxOffset {
+			offset = idetMaxOffset
+		}
+	}
+
+	ctx, cancel := c.encodeContext(context.Background(), queued, idetTimeout)
+	defer cancel()
+
+	cmd := c.newFFmpegCommand(ctx,
+		"-hide_banner",
+		"-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64),
+		"-i", inputPath,
+		"-map", "0:v:0",
+		"-frames:v", strconv.Itoa(idetSampleFrames),
+		"-vf", "idet",
+		"-an", "-f", "null", "-",
+	)
+	output, err := c.processOutput(queued, cmd)
+	if err != nil {
+		return idetResult{}, err
+	}
+	result, ok := parseIdetOutput(string(output))
+	if !ok {
+		return idetResult{}, fmt.Errorf("no idet summary in ffmpeg output")
+	}
+	return result, nil
+}
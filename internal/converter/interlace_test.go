@@ -0,0 +1,30 @@
+package converter
+
+import "testing"
+
+func TestParseIdetOutput(t *testing.T) {
+	output := `[Parsed_idet_0 @ 0x7f] Repeated Fields: Neither:   500 Top:     0 Bottom:     0
+[Parsed_idet_0 @ 0x7f] Single frame detection: TFF:   410 BFF:     0 Progressive:    31 Undetermined:    59
+[Parsed_idet_0 @ 0x7f] Multi frame detection: TFF:   482 BFF:     0 Progressive:     6 Undetermined:    12`
+
+	result, ok := parseIdetOutput(output)
+	if !ok || result != (idetResult{TFF: 482, Progressive: 6}) {
+		t.Fatalf("unexpected idet result: %+v (%v)", result, ok)
+	}
+	if !result.Interlaced() || result.Parity() != "tff" {
+		t.Fatalf("expected interlaced TFF, got %+v", result)
+	}
+
+	if _, ok := parseIdetOutput("no summary"); ok {
+		t.Fatal("expected no result without a summary")
+	}
+}
+
+func TestDeinterlaceFilterRate(t *testing.T) {
+	if got := deinterlaceFilter("bwdif", "field", "tff"); got != "bwdif=mode=send_field:parity=tff:deint=all" {
+		t.Errorf("unexpected field-rate filter: %q", got)
+	}
+	if got := deinterlaceFilter("yadif", "frame", "auto"); got != "yadif=mode=send_frame:parity=auto:deint=all" {
+		t.Errorf("unexpected frame-rate filter: %q", got)
+	}
+}
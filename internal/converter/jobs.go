@@ -0,0 +1,625 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// historyLimit caps the finished files kept for the status API.
+const historyLimit = 10000
+
+// errCancelled is the outcome of a file cancelled before it started.
+var errCancelled = errors.New("cancelled")
+
+// File outcomes recorded in the history
+const (
+	FileDone      = "done"
+	FileFailed    = "failed"
+	FileCancelled = "cancelled"
+)
+
+// JobStatus is a file being converted.
+type JobStatus struct {
+	Path     string         `json:"path"`
+	Type     string         `json:"type"`
+	Started  time.Time      `json:"started"`
+	Encoder  string         `json:"encoder,omitempty"`
+	Percent  float64        `json:"percent"` // -1 when unknown
+	Speed    float64        `json:"speed,omitempty"`
+	ETA      float64        `json:"eta_seconds,omitempty"`
+	Progress *VideoProgress `json:"progress,omitempty"`
+}
+
+// FileRecord is a finished file.
+type FileRecord struct {
+	Path       string    `json:"path"`
+	Type       string    `json:"type"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	InputBytes int64     `json:"input_bytes"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+}
+
+// LimiterStatus describes the worker limit of the current phase.
+type LimiterStatus struct {
+	Adaptive bool `json:"adaptive"`
+	Limit    int  `json:"limit"`
+	Active   int  `json:"active"`
+	MaxJobs  int  `json:"max_jobs"`
+	MaxLimit int  `json:"max_limit"` // highest limit that can be set at runtime
+}
+
+// trackedJob is an active file and the processes working on it.
+type trackedJob struct {
+	status    JobStatus
+	processes map[*os.Process]struct{}
+}
+
+// jobTracker owns the queue of the running phase and follows active and
+// finished files for the dashboard and the HTTP API. It holds the queue
+// controls: pausing stops workers from starting new files, promoting moves
+// files to the front, the limit changes the concurrency and cancelling kills
+// the processes of a file. Outside the run windows the queue is held
+// like a pause, and running ffmpeg processes may be suspended.
+type jobTracker struct {
+	mu        sync.Mutex
+	resumed   *sync.Cond
+	paused    bool
+	phase     string
+	limiter   *AdaptiveLimiter
+	adaptive  bool
+	maxJobs   int
+	maxLimit  int
+	limit     int      // limit set at runtime, kept for the next phase; 0 when unset
+	promoted  []string // promotion targets, applied to later phases too
+	queue     []string
+	active    map[string]*trackedJob
+	cancelled map[string]bool
+	history   []FileRecord
+
+	// Run windows: outside them the held phase ("" for every phase) does
+	// not start new files. suspendSignal is closed when running processes
+	// are suspended, resumeSignal when they continue.
+	outside       bool
+	held          string
+	suspended     bool
+	suspendSignal chan struct{}
+	resumeSignal  chan struct{}
+}
+
+func newJobTracker() *jobTracker {
+	t := &jobTracker{
+		active:        make(map[string]*trackedJob),
+		cancelled:     make(map[string]bool),
+		suspendSignal: make(chan struct{}),
+		resumeSignal:  make(chan struct{}),
+	}
+	close(t.resumeSignal)
+	t.resumed = sync.NewCond(&t.mu)
+	return t
+}
+
+// holding reports whether workers must not start new files. Must be called
+// with t.mu held.
+func (t *jobTracker) holding() bool {
+	if t.paused {
+		return true
+	}
+	return t.outside && (t.held == "" || t.phase == t.held)
+}
+
+// startPhase queues the files of a conversion phase with its worker limiter.
+// maxLimit bounds the limit that can be set at runtime; a limit set during an
+// earlier phase carries over unless adaptive workers control it.
+func (t *jobTracker) startPhase(fileType string, files []string, limiter *AdaptiveLimiter, adaptive bool, maxJobs, maxLimit int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = fileType + "s"
+	t.limiter = limiter
+	t.adaptive = adaptive
+	t.maxJobs = maxJobs
+	t.maxLimit = maxLimit
+	t.queue = append([]string{}, files...)
+	for _, target := range t.promoted {
+		t.promoteLocked(target)
+	}
+	if t.limit > 0 && !adaptive {
+		limiter.SetLimit(clampLimit(t.limit, maxLimit))
+	}
+}
+
+// next pops the first queued file. While the queue is held it returns an
+// empty path with more set; once the queue is empty, more is unset.
+func (t *jobTracker) next() (path string, more bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.holding() {
+		return "", true
+	}
+	if len(t.queue) == 0 {
+		return "", false
+	}
+	path = t.queue[0]
+	t.queue = t.queue[1:]
+	return path, true
+}
+
+// waitWhilePaused blocks a worker while the queue is paused or outside the
+// run windows.
+func (t *jobTracker) waitWhilePaused() {
+	t.mu.Lock()
+	for t.holding() {
+		t.resumed.Wait()
+	}
+	t.mu.Unlock()
+}
+
+// borrowSlot takes a free worker slot of the current phase for extra work of
+// a running file, without waiting. It fails while the queue is paused or held
+// outside the run windows. release gives the slot back.
+func (t *jobTracker) borrowSlot() (release func(), ok bool) {
+	t.mu.Lock()
+	limiter, held := t.limiter, t.holding()
+	t.mu.Unlock()
+	if limiter == nil || held || !limiter.TryAcquire() {
+		return nil, false
+	}
+	return limiter.Release, true
+}
+
+// begin marks a file taken from the queue as active. It returns false when
+// the file was cancelled while queued.
+func (t *jobTracker) begin(path, fileType string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancelled[path] {
+		return false
+	}
+	t.active[path] = &trackedJob{
+		status:    JobStatus{Path: path, Type: fileType, Started: time.Now(), Percent: -1},
+		processes: make(map[*os.Process]struct{}),
+	}
+	return true
+}
+
+// finish moves a file to the history with its outcome.
+func (t *jobTracker) finish(path, fileType string, started time.Time, err error) FileRecord {
+	record := FileRecord{Path: path, Type: fileType, Status: FileDone, Started: started, Finished: time.Now()}
+	if info, statErr := os.Stat(path); statErr == nil {
+		record.InputBytes = info.Size()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case t.cancelled[path]:
+		record.Status = FileCancelled
+	case err != nil:
+		record.Status = FileFailed
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	delete(t.active, path)
+	delete(t.cancelled, path)
+
+	t.history = append(t.history, record)
+	if len(t.history) > historyLimit {
+		t.history = t.history[len(t.history)-historyLimit:]
+	}
+	return record
+}
+
+// attach registers a process working on a file, killing it right away when
+// the file was cancelled. It returns the function detaching it.
+func (t *jobTracker) attach(path string, process *os.Process) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.active[path]
+	if !ok {
+		return func() {}
+	}
+	if t.cancelled[path] {
+		process.Kill()
+	} else if t.suspends(job) {
+		suspendProcess(process)
+	}
+	job.processes[process] = struct{}{}
+	return func() {
+		t.mu.Lock()
+		delete(job.processes, process)
+		t.mu.Unlock()
+	}
+}
+
+// startedAt returns when a worker picked up an active file.
+func (t *jobTracker) startedAt(path string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.active[path]
+	if !ok {
+		return time.Time{}, false
+	}
+	return job.status.Started, true
+}
+
+// update attaches a progress snapshot to the job it belongs to.
+func (t *jobTracker) update(progress VideoProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.active[progress.Job]
+	if !ok {
+		return
+	}
+	job.status.Progress = &progress
+	job.status.Encoder = progress.Encoder
+	job.status.Speed = progress.Speed
+	job.status.Percent = -1
+	if progress.Duration > 0 {
+		job.status.Percent = progress.Percent()
+	}
+	job.status.ETA = 0
+	if eta, ok := progress.ETA(); ok {
+		job.status.ETA = eta.Seconds()
+	}
+}
+
+// setPaused pauses or resumes the queue; in-flight files keep running.
+func (t *jobTracker) setPaused(paused bool) {
+	t.mu.Lock()
+	t.paused = paused
+	t.mu.Unlock()
+	if !paused {
+		t.resumed.Broadcast()
+	}
+}
+
+// setOutside holds or releases the queue as the run windows close and open.
+// phase limits the hold to one phase, "" holding every phase. With suspend,
+// the running processes of the held phase are stopped until the window opens
+// again.
+func (t *jobTracker) setOutside(outside bool, phase string, suspend bool) {
+	t.mu.Lock()
+	t.outside = outside
+	t.held = phase
+	switch {
+	case outside && suspend && !t.suspended:
+		t.suspended = true
+		for _, job := range t.active {
+			if !t.suspends(job) {
+				continue
+			}
+			for process := range job.processes {
+				suspendProcess(process)
+			}
+		}
+		close(t.suspendSignal)
+		t.resumeSignal = make(chan struct{})
+	case !outside && t.suspended:
+		t.suspended = false
+		for _, job := range t.active {
+			for process := range job.processes {
+				resumeProcess(process)
+			}
+		}
+		close(t.resumeSignal)
+		t.suspendSignal = make(chan struct{})
+	}
+	t.mu.Unlock()
+	if !outside {
+		t.resumed.Broadcast()
+	}
+}
+
+// suspends reports whether the processes of an active file are held
+// stopped, with t.mu held.
+func (t *jobTracker) suspends(job *trackedJob) bool {
+	return t.suspended && t.suspendsType(job.status.Type)
+}
+
+// suspendsType reports whether suspending outside the run windows applies
+// to files of a type, with t.mu held.
+func (t *jobTracker) suspendsType(fileType string) bool {
+	return t.held == "" || fileType+"s" == t.held
+}
+
+// suspension returns a channel closed once the running processes of a file
+// are suspended, nil when the held phase leaves them running, and
+// resumption one closed once they continue.
+func (t *jobTracker) suspension(path string) <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fileType := strings.TrimSuffix(t.phase, "s") // not started yet
+	if job, ok := t.active[path]; ok {
+		fileType = job.status.Type
+	}
+	if !t.suspendsType(fileType) {
+		return nil
+	}
+	return t.suspendSignal
+}
+
+func (t *jobTracker) resumption() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resumeSignal
+}
+
+// cancel stops a queued or active file. Active files lose their running
+// processes.
+func (t *jobTracker) cancel(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, active := t.active[path]
+	if !active && !t.isQueued(path) {
+		return fmt.Errorf("%s is neither queued nor being converted", path)
+	}
+	t.cancelled[path] = true
+	if active {
+		for process := range job.processes {
+			process.Kill()
+		}
+	}
+	return nil
+}
+
+// isCancelled reports whether a queued or running file was cancelled.
+func (t *jobTracker) isCancelled(path string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cancelled[path]
+}
+
+func (t *jobTracker) isQueued(path string) bool {
+	for _, queued := range t.queue {
+		if queued == path {
+			return true
+		}
+	}
+	return false
+}
+
+// promote moves the queued files matching target, a file or a folder, to the
+// front of the queue in their current order. The target is remembered so
+// files of later phases are promoted as well. It returns the number of files
+// moved.
+func (t *jobTracker) promote(target string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.promoted = append(t.promoted, target)
+	return t.promoteLocked(target)
+}
+
+func (t *jobTracker) promoteLocked(target string) int {
+	var front, rest []string
+	for _, path := range t.queue {
+		if pathWithin(path, target) {
+			front = append(front, path)
+		} else {
+			rest = append(rest, path)
+		}
+	}
+	t.queue = append(front, rest...)
+	return len(front)
+}
+
+// sourcePath resolves a path given by the user against the source directory,
+// unless it is absolute or already names a path inside it.
+func (c *Converter) sourcePath(path string) string {
+	if !filepath.IsAbs(path) && !pathWithin(path, c.config.SourceDir) {
+		return filepath.Join(c.config.SourceDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// pathWithin reports whether path is target or lies in the target folder.
+func pathWithin(path, target string) bool {
+	path, target = filepath.Clean(path), filepath.Clean(target)
+	return path == target || strings.HasPrefix(path, target+string(filepath.Separator))
+}
+
+// setLimit changes the number of concurrent files of the current phase and of
+// the following ones. With adaptive workers, the controller keeps adjusting
+// the limit from the new value.
+func (t *jobTracker) setLimit(limit int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit = limit
+	if t.limiter == nil {
+		return limit
+	}
+	limit = clampLimit(limit, t.maxLimit)
+	t.limiter.SetLimit(limit)
+	return limit
+}
+
+func clampLimit(limit, maxLimit int) int {
+	if limit < 1 {
+		return 1
+	}
+	if maxLimit > 0 && limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+// jobs returns the active files, oldest first.
+func (t *jobTracker) jobs() []JobStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	jobs := make([]JobStatus, 0, len(t.active))
+	for _, job := range t.active {
+		jobs = append(jobs, job.status)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Started.Before(jobs[j].Started) })
+	return jobs
+}
+
+// limits returns the worker limits of the current phase.
+func (t *jobTracker) limits() LimiterStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status := LimiterStatus{Adaptive: t.adaptive, MaxJobs: t.maxJobs, MaxLimit: t.maxLimit, Limit: t.maxJobs, Active: len(t.active)}
+	if t.limiter != nil {
+		status.Limit = t.limiter.Limit()
+		status.Active = t.limiter.Active()
+	}
+	return status
+}
+
+// StatsSnapshot is a copy of the conversion counters.
+type StatsSnapshot struct {
+	TotalFiles        int     `json:"total_files"`
+	ProcessedFiles    int     `json:"processed_files"`
+	FailedFiles       int     `json:"failed_files"`
+	SkippedFiles      int     `json:"skipped_files"`
+	RuleSkippedFiles  int     `json:"rule_skipped_files"`
+	CopiedFiles       int     `json:"copied_files"`
+	RemuxedFiles      int     `json:"remuxed_files"`
+	CancelledFiles    int     `json:"cancelled_files"`
+	KeptOriginals     int     `json:"kept_originals"`
+	HDRPreserved      int     `json:"hdr_preserved"`
+	HDRTonemapped     int     `json:"hdr_tonemapped"`
+	MetadataLossFiles int     `json:"metadata_loss_files"`
+	DeinterlacedFiles int     `json:"deinterlaced_files"`
+	SlowMotionLost    int     `json:"slow_motion_lost"`
+	RecoveredFiles    int     `json:"recovered_files"`
+	TotalSizeMB       float64 `json:"total_size_mb"`
+	ProcessedSizeMB   float64 `json:"processed_size_mb"`
+	OutputSizeMB      float64 `json:"output_size_mb"`
+	SavedSizeMB       float64 `json:"saved_size_mb"`
+}
+
+// RunStatus is the state of the conversion run.
+type RunStatus struct {
+	Started time.Time     `json:"started"`
+	Elapsed float64       `json:"elapsed_seconds"`
+	Phase   string        `json:"phase"`
+	Paused  bool          `json:"paused"`
+	Queued  int           `json:"queued"`
+	Stats   StatsSnapshot `json:"stats"`
+	Limiter LimiterStatus `json:"limiter"`
+	Jobs    []JobStatus   `json:"jobs"`
+
+	// Outside the run windows, with running files suspended or not
+	OutsideWindow bool `json:"outside_window"`
+	Suspended     bool `json:"suspended"`
+}
+
+// Stats returns a copy of the conversion counters.
+func (c *Converter) Stats() StatsSnapshot {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+	return StatsSnapshot{
+		TotalFiles:        c.stats.totalFiles,
+		ProcessedFiles:    c.stats.processedFiles,
+		FailedFiles:       c.stats.failedFiles,
+		SkippedFiles:      c.stats.skippedFiles,
+		RuleSkippedFiles:  c.stats.ruleSkippedFiles,
+		CopiedFiles:       c.stats.copiedFiles,
+		RemuxedFiles:      c.stats.remuxedFiles,
+		CancelledFiles:    c.stats.cancelledFiles,
+		KeptOriginals:     len(c.stats.keptOriginals),
+		HDRPreserved:      c.stats.hdrPreserved,
+		HDRTonemapped:     c.stats.hdrTonemapped,
+		MetadataLossFiles: c.stats.metadataLossFiles,
+		DeinterlacedFiles: c.stats.deinterlacedFiles,
+		SlowMotionLost:    c.stats.slowMotionLost,
+		RecoveredFiles:    c.stats.recoveredFiles,
+		TotalSizeMB:       c.stats.totalSizeMB,
+		ProcessedSizeMB:   c.stats.processedSizeMB,
+		OutputSizeMB:      c.stats.outputSizeMB,
+		SavedSizeMB:       c.stats.savedSizeMB,
+	}
+}
+
+// Status returns the run state, counters and active files.
+func (c *Converter) Status() RunStatus {
+	status := RunStatus{
+		Stats:   c.Stats(),
+		Limiter: c.jobs.limits(),
+		Jobs:    c.jobs.jobs(),
+	}
+	c.stats.mu.Lock()
+	status.Started = c.stats.startTime
+	c.stats.mu.Unlock()
+	status.Elapsed = time.Since(status.Started).Seconds()
+
+	c.jobs.mu.Lock()
+	status.Phase = c.jobs.phase
+	status.Paused = c.jobs.paused
+	status.Queued = len(c.jobs.queue)
+	status.OutsideWindow = c.jobs.outside
+	status.Suspended = c.jobs.suspended
+	c.jobs.mu.Unlock()
+	return status
+}
+
+// History returns the finished files, oldest first.
+func (c *Converter) History() []FileRecord {
+	c.jobs.mu.Lock()
+	defer c.jobs.mu.Unlock()
+	return append([]FileRecord{}, c.jobs.history...)
+}
+
+// Pause stops workers from starting new files; running files finish.
+func (c *Converter) Pause() {
+	c.jobs.setPaused(true)
+	c.logger.Info("⏸️  Queue paused")
+}
+
+// Resume lets workers start new files again.
+func (c *Converter) Resume() {
+	c.jobs.setPaused(false)
+	c.logger.Info("▶️  Queue resumed")
+}
+
+// Queue returns the files waiting in the current phase, in the order they
+// will be picked up.
+func (c *Converter) Queue() []string {
+	c.jobs.mu.Lock()
+	defer c.jobs.mu.Unlock()
+	return append([]string{}, c.jobs.queue...)
+}
+
+// Promote moves the queued files matching target, a file or a folder, to the
+// front of the queue. Relative targets are resolved against the source
+// directory. It returns the number of files moved in the current phase;
+// matching files of a later phase are promoted when it starts.
+func (c *Converter) Promote(target string) int {
+	target = c.sourcePath(target)
+	moved := c.jobs.promote(target)
+	c.logger.Info(fmt.Sprintf("⏫ Promoted %s (%d queued files moved to the front)", target, moved))
+	return moved
+}
+
+// SetConcurrency changes how many files are converted at once, from the
+// current phase on. Running files are never interrupted: lowering the limit
+// takes effect as they finish. It returns the limit applied, bounded by the
+// number of workers.
+func (c *Converter) SetConcurrency(limit int) (int, error) {
+	if limit < 1 {
+		return 0, fmt.Errorf("concurrency must be at least 1, got %d", limit)
+	}
+	applied := c.jobs.setLimit(limit)
+	c.logger.Info(fmt.Sprintf("⚙️  Concurrency set to %d", applied))
+	return applied, nil
+}
+
+// Cancel stops a queued or running file, identified by its source path.
+// Relative paths are resolved against the source directory.
+func (c *Converter) Cancel(path string) error {
+	path = c.sourcePath(path)
+	if err := c.jobs.cancel(path); err != nil {
+		return err
+	}
+	c.logger.Warn(fmt.Sprintf("⏹️  Cancelling %s", path))
+	return nil
+}
@@ -0,0 +1,187 @@
+package converter
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/config"
+)
+
+func TestJobTracker(t *testing.T) {
+	tracker := newJobTracker()
+	tracker.startPhase("video", []string{"a.mov", "b.mov", "c.mov"}, NewAdaptiveLimiter(2), false, 2, 8)
+
+	if path, _ := tracker.next(); path != "a.mov" {
+		t.Fatalf("expected a.mov first, got %q", path)
+	}
+	if !tracker.begin("a.mov", "video") {
+		t.Fatal("a.mov should start")
+	}
+	tracker.update(VideoProgress{Job: "a.mov", Encoder: "libx265", Duration: 10 * time.Second, OutTime: 5 * time.Second, Speed: 2})
+
+	jobs := tracker.jobs()
+	if len(jobs) != 1 || jobs[0].Encoder != "libx265" || jobs[0].Percent != 50 || jobs[0].Speed != 2 {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+	if limits := tracker.limits(); limits.Adaptive || limits.Limit != 2 || limits.MaxLimit != 8 {
+		t.Errorf("unexpected limits: %+v", limits)
+	}
+
+	// A queued file cancelled before a worker picks it up never starts
+	if err := tracker.cancel("b.mov"); err != nil {
+		t.Fatal(err)
+	}
+	tracker.next()
+	if tracker.begin("b.mov", "video") {
+		t.Error("cancelled file should not start")
+	}
+	if record := tracker.finish("b.mov", "video", time.Now(), errCancelled); record.Status != FileCancelled {
+		t.Errorf("expected cancelled record, got %+v", record)
+	}
+
+	if record := tracker.finish("a.mov", "video", time.Now(), nil); record.Status != FileDone {
+		t.Errorf("expected done record, got %+v", record)
+	}
+	tracker.next()
+	tracker.begin("c.mov", "video")
+	if record := tracker.finish("c.mov", "video", time.Now(), errors.New("corrupted")); record.Status != FileFailed || record.Error != "corrupted" {
+		t.Errorf("expected failed record, got %+v", record)
+	}
+
+	if err := tracker.cancel("unknown.mov"); err == nil {
+		t.Error("cancelling an unknown file should fail")
+	}
+	if _, more := tracker.next(); more {
+		t.Error("queue should be empty")
+	}
+	if len(tracker.jobs()) != 0 || len(tracker.history) != 3 {
+		t.Errorf("expected no active job and 3 finished files, got %d and %d", len(tracker.jobs()), len(tracker.history))
+	}
+}
+
+func TestJobTrackerPause(t *testing.T) {
+	tracker := newJobTracker()
+	tracker.setPaused(true)
+
+	resumed := make(chan struct{})
+	go func() {
+		tracker.waitWhilePaused()
+		close(resumed)
+	}()
+
+	select {
+	case <-resumed:
+		t.Fatal("worker should wait while the queue is paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tracker.setPaused(false)
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Fatal("worker should continue once the queue is resumed")
+	}
+}
+
+func TestJobTrackerPromote(t *testing.T) {
+	tracker := newJobTracker()
+	tracker.promote("/src/2023/trip")
+	tracker.startPhase("photo", []string{"/src/a.jpg", "/src/2023/trip/b.jpg", "/src/2023/trip-old/c.jpg", "/src/d.jpg", "/src/2023/trip/e.jpg"}, NewAdaptiveLimiter(2), false, 2, 8)
+
+	// Targets promoted before the phase started are applied to its files
+	expected := []string{"/src/2023/trip/b.jpg", "/src/2023/trip/e.jpg", "/src/a.jpg", "/src/2023/trip-old/c.jpg", "/src/d.jpg"}
+	if !reflect.DeepEqual(tracker.queue, expected) {
+		t.Fatalf("expected %v, got %v", expected, tracker.queue)
+	}
+
+	if moved := tracker.promote("/src/d.jpg"); moved != 1 || tracker.queue[0] != "/src/d.jpg" {
+		t.Errorf("expected d.jpg first, moved %d: %v", moved, tracker.queue)
+	}
+
+	tracker.setPaused(true)
+	if path, more := tracker.next(); path != "" || !more {
+		t.Errorf("paused queue should hand out nothing, got %q", path)
+	}
+}
+
+func TestJobTrackerSetLimit(t *testing.T) {
+	tracker := newJobTracker()
+	limiter := NewAdaptiveLimiter(2)
+	tracker.startPhase("video", nil, limiter, false, 2, 4)
+
+	if applied := tracker.setLimit(10); applied != 4 || limiter.Limit() != 4 {
+		t.Errorf("expected the limit to be capped at 4, got %d", limiter.Limit())
+	}
+	tracker.setLimit(1)
+
+	// The limit carries over to the next phase
+	next := NewAdaptiveLimiter(4)
+	tracker.startPhase("photo", nil, next, false, 4, 8)
+	if next.Limit() != 1 {
+		t.Errorf("expected the runtime limit to carry over, got %d", next.Limit())
+	}
+
+	// Adaptive workers keep their own starting limit
+	adaptive := NewAdaptiveLimiter(2)
+	tracker.startPhase("video", nil, adaptive, true, 4, 8)
+	if adaptive.Limit() != 2 {
+		t.Errorf("adaptive limiter should keep its limit, got %d", adaptive.Limit())
+	}
+}
+
+func TestCancelResolvesRelativePaths(t *testing.T) {
+	c := newCopyTestConverter(t)
+	c.config.SourceDir = "/photos"
+	c.jobs.startPhase("video", []string{"/photos/trip/a.mov", "/photos/trip/b.mov"}, NewAdaptiveLimiter(1), false, 1, 1)
+
+	if err := c.Cancel("trip/a.mov"); err != nil {
+		t.Fatalf("relative path should resolve against the source directory: %v", err)
+	}
+	if err := c.Cancel("/photos/trip/../trip/b.mov"); err != nil {
+		t.Fatalf("absolute path should be cleaned: %v", err)
+	}
+	if !c.jobs.cancelled["/photos/trip/a.mov"] || !c.jobs.cancelled["/photos/trip/b.mov"] {
+		t.Errorf("expected both files cancelled, got %v", c.jobs.cancelled)
+	}
+}
+
+func TestCancelKillsAnyChildProcess(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+	c := &Converter{config: &config.Config{}, jobs: newJobTracker()}
+	c.jobs.startPhase("photo", []string{"a.jpg"}, NewAdaptiveLimiter(1), false, 1, 1)
+	c.jobs.next()
+	c.jobs.begin("a.jpg", "photo")
+
+	done := make(chan error, 1)
+	go func() { done <- c.runProcess("a.jpg", exec.Command("sleep", "5")) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for attachedProcesses(c.jobs, "a.jpg") == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := c.jobs.cancel("a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("the cancelled process should be killed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the cancelled process is still running")
+	}
+	if n := attachedProcesses(c.jobs, "a.jpg"); n != 0 {
+		t.Errorf("expected the process detached, %d still attached", n)
+	}
+}
+
+func attachedProcesses(t *jobTracker, path string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active[path].processes)
+}
@@ -0,0 +1,104 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// preservedVideoTags are the container tags checked after every conversion.
+// Apple devices store them as QuickTime keys, other cameras as classic tags.
+var preservedVideoTags = []string{
+	"com.apple.quicktime.location.ISO6709",
+	"com.apple.quicktime.make",
+	"com.apple.quicktime.model",
+	"com.apple.quicktime.software",
+	"com.apple.quicktime.creationdate",
+	slowMotionIntentTag,
+	"location",
+	"make",
+	"model",
+	"creation_time",
+}
+
+// videoMetadata holds the key tags read from a source and, for high-speed
+// footage, the frame rate to keep. Orientation is checked by the output
+// verification.
+type videoMetadata struct {
+	Tags      map[string]string
+	FrameRate float64
+}
+
+// readVideoMetadata collects the preserved tags and high-speed frame rate of a source.
+func readVideoMetadata(probe *utils.MediaProbe) videoMetadata {
+	meta := videoMetadata{Tags: make(map[string]string)}
+	for _, key := range preservedVideoTags {
+		if value := probe.Tag(key); value != "" {
+			meta.Tags[key] = value
+		}
+	}
+	if video := probe.VideoStream(); video != nil && video.FrameRate() > highSpeedFrameRate {
+		meta.FrameRate = video.FrameRate()
+	}
+	return meta
+}
+
+// metadataArgs writes the key tags explicitly so they survive as MP4 metadata
+// keys. The generic location and make/model tags are filled from their Apple
+// counterparts for readers that only know the classic atoms.
+func (m videoMetadata) metadataArgs() []string {
+	tags := make(map[string]string, len(m.Tags))
+	for key, value := range m.Tags {
+		tags[key] = value
+	}
+	for apple, generic := range map[string]string{
+		"com.apple.quicktime.location.ISO6709": "location",
+		"com.apple.quicktime.make":             "make",
+		"com.apple.quicktime.model":            "model",
+	} {
+		if value, ok := tags[apple]; ok && tags[generic] == "" {
+			tags[generic] = value
+		}
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := []string{}
+	for _, key := range keys {
+		// creation_time is copied by -map_metadata and kept in mvhd
+		if key == "creation_time" {
+			continue
+		}
+		args = append(args, "-metadata", fmt.Sprintf("%s=%s", key, tags[key]))
+	}
+	return args
+}
+
+// diffVideoMetadata lists the source tags missing or changed in the output and
+// reports a high-speed frame rate not kept.
+func diffVideoMetadata(source videoMetadata, output *utils.MediaProbe) []string {
+	var lost []string
+	for key, value := range source.Tags {
+		actual := output.Tag(key)
+		switch {
+		case actual == "":
+			lost = append(lost, key)
+		case key != "creation_time" && strings.TrimSpace(actual) != strings.TrimSpace(value):
+			lost = append(lost, fmt.Sprintf("%s (%q -> %q)", key, value, actual))
+		}
+	}
+	sort.Strings(lost)
+
+	if video := output.VideoStream(); video != nil && source.FrameRate > 0 {
+		if rate := video.FrameRate(); rate < source.FrameRate*0.9 {
+			lost = append(lost, fmt.Sprintf("frame rate (%s -> %s fps)", formatFrameRate(source.FrameRate), formatFrameRate(rate)))
+		}
+	}
+	return lost
+}
@@ -0,0 +1,273 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Histogram buckets: conversion time in seconds and output/input size ratio
+var (
+	durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200}
+	ratioBuckets    = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1, 1.25}
+)
+
+// File events counted per media type
+const (
+	eventFailed    = "failed"
+	eventSkipped   = "skipped"
+	eventRecovered = "recovered"
+	eventCancelled = "cancelled"
+)
+
+// outputKey identifies a media type and the codec it was converted to.
+type outputKey struct {
+	fileType string
+	codec    string
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // cumulative is computed on export
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// metrics accumulates the counters exported on /metrics. The conversion
+// counters of ConversionStats stay the source of the final report; these
+// add the media type and codec labels Prometheus needs.
+type metrics struct {
+	mu          sync.Mutex
+	converted   map[outputKey]float64
+	inputBytes  map[outputKey]float64
+	outputBytes map[outputKey]float64
+	durations   map[outputKey]*histogram
+	ratios      map[outputKey]*histogram
+	events      map[string]map[string]float64 // event -> media type -> count
+	lastFinish  time.Time
+	resources   ResourceSnapshot
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		converted:   make(map[outputKey]float64),
+		inputBytes:  make(map[outputKey]float64),
+		outputBytes: make(map[outputKey]float64),
+		durations:   make(map[outputKey]*histogram),
+		ratios:      make(map[outputKey]*histogram),
+		events:      make(map[string]map[string]float64),
+	}
+}
+
+// fileConverted records a file written to the destination. A zero duration
+// leaves the duration histogram untouched.
+func (m *metrics) fileConverted(fileType, codec string, inputBytes, outputBytes float64, duration time.Duration) {
+	key := outputKey{fileType: fileType, codec: codec}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.converted[key]++
+	m.inputBytes[key] += inputBytes
+	m.outputBytes[key] += outputBytes
+	if duration > 0 {
+		if m.durations[key] == nil {
+			m.durations[key] = newHistogram(durationBuckets)
+		}
+		m.durations[key].observe(duration.Seconds())
+	}
+	if inputBytes > 0 {
+		if m.ratios[key] == nil {
+			m.ratios[key] = newHistogram(ratioBuckets)
+		}
+		m.ratios[key].observe(outputBytes / inputBytes)
+	}
+}
+
+// fileEvent counts a failed, skipped, recovered or cancelled file.
+func (m *metrics) fileEvent(event, fileType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.events[event] == nil {
+		m.events[event] = make(map[string]float64)
+	}
+	m.events[event][fileType]++
+}
+
+// fileFinished marks the time a worker last finished a file, whatever the
+// outcome, so a stalled run can be told from a slow one.
+func (m *metrics) fileFinished() {
+	m.mu.Lock()
+	m.lastFinish = time.Now()
+	m.mu.Unlock()
+}
+
+// observeResources records the resource monitor readings on their way to
+// the adaptive controller.
+func (m *metrics) observeResources(snapshots <-chan ResourceSnapshot) <-chan ResourceSnapshot {
+	out := make(chan ResourceSnapshot, 1)
+	go func() {
+		defer close(out)
+		for snap := range snapshots {
+			m.mu.Lock()
+			m.resources = snap
+			m.mu.Unlock()
+			out <- snap
+		}
+	}()
+	return out
+}
+
+// WriteMetrics writes the conversion and resource metrics in the Prometheus
+// text exposition format.
+func (c *Converter) WriteMetrics(w io.Writer) error {
+	status := c.Status()
+	m := c.metrics
+
+	var out strings.Builder
+	gauge := func(name, help string, value float64) {
+		writeFamily(&out, name, help, "gauge")
+		writeSample(&out, name, nil, value)
+	}
+
+	m.mu.Lock()
+	writeOutputCounter(&out, "media_converter_files_converted_total", "Files written to the destination.", m.converted)
+	for _, event := range []string{eventFailed, eventSkipped, eventRecovered, eventCancelled} {
+		name := "media_converter_files_" + event + "_total"
+		writeFamily(&out, name, fmt.Sprintf("Files %s, by media type.", event), "counter")
+		for _, fileType := range sortedKeys(m.events[event]) {
+			writeSample(&out, name, []string{"type", fileType}, m.events[event][fileType])
+		}
+	}
+	writeOutputCounter(&out, "media_converter_input_bytes_total", "Size of the converted source files.", m.inputBytes)
+	writeOutputCounter(&out, "media_converter_output_bytes_total", "Size of the written output files.", m.outputBytes)
+	writeHistograms(&out, "media_converter_conversion_duration_seconds", "Time spent converting a file.", m.durations)
+	writeHistograms(&out, "media_converter_compression_ratio", "Output size divided by source size.", m.ratios)
+
+	if !m.lastFinish.IsZero() {
+		gauge("media_converter_last_file_finished_timestamp_seconds", "Unix time a worker last finished a file.", float64(m.lastFinish.UnixNano())/1e9)
+	}
+	resources := m.resources
+	m.mu.Unlock()
+
+	if resources.CPUMeasured {
+		gauge("media_converter_cpu_usage_percent", "CPU usage sampled by the adaptive worker monitor, relative to the encoder limits when set.", resources.CPUPercent)
+	}
+	if resources.MemMeasured {
+		gauge("media_converter_memory_available_percent", "Available memory sampled by the adaptive worker monitor, relative to the encoder limits when set.", resources.MemAvailablePercent)
+	}
+
+	gauge("media_converter_start_time_seconds", "Unix time the run started.", float64(status.Started.UnixNano())/1e9)
+	gauge("media_converter_files", "Files found for this run.", float64(status.Stats.TotalFiles))
+	gauge("media_converter_queued_files", "Files of the current phase not yet picked up by a worker.", float64(status.Queued))
+	gauge("media_converter_active_jobs", "Files being converted.", float64(len(status.Jobs)))
+	gauge("media_converter_paused", "1 when the queue is paused.", boolValue(status.Paused))
+	gauge("media_converter_outside_window", "1 outside the run windows.", boolValue(status.OutsideWindow))
+	gauge("media_converter_suspended", "1 while running encodes are suspended outside the run windows.", boolValue(status.Suspended))
+	gauge("media_converter_workers_adaptive", "1 when the adaptive limiter controls the workers.", boolValue(status.Limiter.Adaptive))
+	gauge("media_converter_workers_limit", "Worker limit of the current phase.", float64(status.Limiter.Limit))
+	gauge("media_converter_workers_active", "Workers holding a limiter slot.", float64(status.Limiter.Active))
+	gauge("media_converter_workers_max", "Maximum workers of the current phase.", float64(status.Limiter.MaxJobs))
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func writeFamily(out *strings.Builder, name, help, kind string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes one sample; labels alternate names and values.
+func writeSample(out *strings.Builder, name string, labels []string, value float64) {
+	out.WriteString(name)
+	if len(labels) > 0 {
+		out.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			fmt.Fprintf(out, "%s=%q", labels[i], labels[i+1])
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(' ')
+	out.WriteString(formatMetricValue(value))
+	out.WriteByte('\n')
+}
+
+func writeOutputCounter(out *strings.Builder, name, help string, values map[outputKey]float64) {
+	writeFamily(out, name, help+" By media type and codec.", "counter")
+	for _, key := range sortedOutputKeys(values) {
+		writeSample(out, name, []string{"type", key.fileType, "codec", key.codec}, values[key])
+	}
+}
+
+func writeHistograms(out *strings.Builder, name, help string, histograms map[outputKey]*histogram) {
+	writeFamily(out, name, help+" By media type and codec.", "histogram")
+	for _, key := range sortedOutputKeys(histograms) {
+		h := histograms[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += h.counts[i]
+			writeSample(out, name+"_bucket", []string{"type", key.fileType, "codec", key.codec, "le", formatMetricValue(bound)}, float64(cumulative))
+		}
+		writeSample(out, name+"_bucket", []string{"type", key.fileType, "codec", key.codec, "le", "+Inf"}, float64(h.count))
+		writeSample(out, name+"_sum", []string{"type", key.fileType, "codec", key.codec}, h.sum)
+		writeSample(out, name+"_count", []string{"type", key.fileType, "codec", key.codec}, float64(h.count))
+	}
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedOutputKeys[V any](values map[outputKey]V) []outputKey {
+	keys := make([]outputKey, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].fileType != keys[j].fileType {
+			return keys[i].fileType < keys[j].fileType
+		}
+		return keys[i].codec < keys[j].codec
+	})
+	return keys
+}
@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	c := &Converter{
+		stats:   &ConversionStats{startTime: time.Unix(1700000000, 0)},
+		jobs:    newJobTracker(),
+		metrics: newMetrics(),
+	}
+	c.jobs.startPhase("video", []string{"a.mov", "b.mov"}, NewAdaptiveLimiter(2), true, 4, 8)
+	c.metrics.fileConverted("video", "h265", 1000, 400, 90*time.Second)
+	c.metrics.fileConverted("photo", "avif", 200, 50, 0)
+	c.metrics.fileEvent(eventFailed, "video")
+	c.metrics.fileEvent(eventSkipped, "photo")
+	c.metrics.fileEvent(eventSkipped, "photo")
+
+	var out strings.Builder
+	if err := c.WriteMetrics(&out); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+
+	for _, expected := range []string{
+		`# TYPE media_converter_files_converted_total counter`,
+		`media_converter_files_converted_total{type="video",codec="h265"} 1`,
+		`media_converter_files_failed_total{type="video"} 1`,
+		`media_converter_files_skipped_total{type="photo"} 2`,
+		`media_converter_input_bytes_total{type="photo",codec="avif"} 200`,
+		`media_converter_output_bytes_total{type="video",codec="h265"} 400`,
+		`media_converter_conversion_duration_seconds_bucket{type="video",codec="h265",le="60"} 0`,
+		`media_converter_conversion_duration_seconds_bucket{type="video",codec="h265",le="120"} 1`,
+		`media_converter_conversion_duration_seconds_bucket{type="video",codec="h265",le="+Inf"} 1`,
+		`media_converter_conversion_duration_seconds_sum{type="video",codec="h265"} 90`,
+		`media_converter_compression_ratio_bucket{type="photo",codec="avif",le="0.3"} 1`,
+		`media_converter_compression_ratio_count{type="video",codec="h265"} 1`,
+		`media_converter_queued_files 2`,
+		`media_converter_workers_adaptive 1`,
+		`media_converter_workers_limit 2`,
+		`media_converter_workers_max 4`,
+		`media_converter_start_time_seconds 1.7e+09`,
+	} {
+		if !strings.Contains(text, expected+"\n") {
+			t.Errorf("metrics are missing %q:\n%s", expected, text)
+		}
+	}
+
+	// Files without a measured duration do not skew the histogram
+	if strings.Contains(text, `media_converter_conversion_duration_seconds_count{type="photo"`) {
+		t.Error("photo without a duration should not be observed")
+	}
+	// Resource gauges only appear once the monitor has sampled them
+	if strings.Contains(text, "media_converter_cpu_usage_percent") {
+		t.Error("unexpected CPU gauge without a sample")
+	}
+}
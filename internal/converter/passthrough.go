@@ -0,0 +1,175 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// passthroughDecision explains why a source is copied or remuxed instead of
+// being transcoded.
+type passthroughDecision struct {
+	Remux  bool
+	Reason string
+}
+
+// Relative compression efficiency of common image codecs. Only the ratio
+// between source and target matters.
+var imageCodecEfficiency = map[string]float64{
+	"BMP":  0.2,
+	"TIFF": 0.3,
+	"PNG":  0.5,
+	"GIF":  0.7,
+	"JPEG": 1.0,
+	"WEBP": 1.7,
+	"HEIC": 1.9,
+	"AVIF": 2.0,
+	"JXL":  2.0,
+}
+
+// Approximate bits per pixel per frame produced by each target codec at
+// CRF 28. Used to estimate the output bitrate before encoding.
+var videoTargetBitsPerPixel = map[string]float64{
+	"h264": 0.08,
+	"h265": 0.05,
+	"av1":  0.04,
+}
+
+// Relative efficiency of video codecs, used when bitrate data is missing.
+var videoCodecEfficiency = map[string]float64{
+	"mpeg2video": 0.6,
+	"mpeg4":      0.7,
+	"h264":       1.0,
+	"vp9":        1.6,
+	"hevc":       1.6,
+	"av1":        2.0,
+}
+
+// probeCodecNames maps normalised target codecs to ffprobe codec names.
+var probeCodecNames = map[string]string{
+	"h264": "h264",
+	"h265": "hevc",
+	"av1":  "av1",
+}
+
+// evaluatePassthrough probes a source and decides whether it should bypass
+// transcoding. It returns false when the file should be converted normally.
+func (c *Converter) evaluatePassthrough(inputPath string, plan conversionPlan) (passthroughDecision, bool) {
+	if utils.HasExtension(inputPath, c.config.CopyThrough.Extensions) {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(inputPath), "."))
+		return passthroughDecision{Reason: fmt.Sprintf("%s kept as original", ext)}, true
+	}
+
+	switch plan.MediaType {
+	case "photo":
+		return c.evaluateImagePassthrough(inputPath, plan)
+	case "video":
+		return c.evaluateVideoPassthrough(inputPath, plan)
+	default:
+		return passthroughDecision{}, false
+	}
+}
+
+func (c *Converter) evaluateImagePassthrough(inputPath string, plan conversionPlan) (passthroughDecision, bool) {
+	format, err := utils.GetImageFormat(inputPath)
+	if err != nil {
+		return passthroughDecision{}, false
+	}
+
+	sourceEff, ok := imageCodecEfficiency[format]
+	if !ok {
+		return passthroughDecision{}, false
+	}
+	targetEff := imageCodecEfficiency[strings.ToUpper(plan.Format)]
+	if targetEff == 0 {
+		return passthroughDecision{}, false
+	}
+
+	gain := (1 - sourceEff/targetEff) * 100
+	if gain >= c.config.CopyThrough.MinGainPercent {
+		return passthroughDecision{}, false
+	}
+
+	return passthroughDecision{
+		Reason: fmt.Sprintf("already %s, est. gain %.0f%% < %.0f%%", format, math.Max(gain, 0), c.config.CopyThrough.MinGainPercent),
+	}, true
+}
+
+func (c *Converter) evaluateVideoPassthrough(inputPath string, plan conversionPlan) (passthroughDecision, bool) {
+	probe, err := utils.ProbeMedia(inputPath)
+	if err != nil {
+		return passthroughDecision{}, false
+	}
+
+	stream := probe.VideoStream()
+	if stream == nil {
+		return passthroughDecision{}, false
+	}
+
+	targetCodec := normalizeVideoCodec(plan.Codec)
+	gain, ok := estimateVideoGain(probe, stream, targetCodec, plan.Quality)
+	if !ok || gain >= c.config.CopyThrough.MinGainPercent {
+		return passthroughDecision{}, false
+	}
+
+	decision := passthroughDecision{
+		Reason: fmt.Sprintf("%s at %.1f Mbps, est. gain %.0f%% < %.0f%%",
+			stream.CodecName, float64(videoBitrate(probe, stream))/1_000_000, math.Max(gain, 0), c.config.CopyThrough.MinGainPercent),
+	}
+
+	// HEVC in an MP4/MOV container only needs the hvc1 tag and faststart layout
+	if c.config.CopyThrough.Remux && stream.CodecName == "hevc" && utils.HasExtension(inputPath, []string{"mp4", "mov", "m4v"}) {
+		fastStart, fsErr := utils.IsFastStart(inputPath)
+		if stream.CodecTagString != "hvc1" || fsErr != nil || !fastStart || !utils.HasExtension(inputPath, []string{"mp4"}) {
+			decision.Remux = true
+		}
+	}
+
+	return decision, true
+}
+
+// estimateVideoGain predicts the size reduction (in percent) of re-encoding the
+// stream with the target codec. Bitrate-based estimation is preferred; codec
+// efficiency is used when the source bitrate or geometry is unknown.
+func estimateVideoGain(probe *utils.MediaProbe, stream *utils.ProbeStream, targetCodec string, crf int) (float64, bool) {
+	sourceBitrate := videoBitrate(probe, stream)
+	fps := stream.FrameRate()
+	bpp, known := videoTargetBitsPerPixel[targetCodec]
+
+	if sourceBitrate > 0 && fps > 0 && stream.Width > 0 && stream.Height > 0 && known {
+		// Each 6 CRF steps roughly halves (or doubles) the bitrate
+		bpp *= math.Pow(2, float64(28-crf)/6)
+		expected := bpp * float64(stream.Width*stream.Height) * fps
+		return (1 - expected/float64(sourceBitrate)) * 100, true
+	}
+
+	sourceEff, ok := videoCodecEfficiency[stream.CodecName]
+	if !ok {
+		return 0, false
+	}
+	targetEff := videoCodecEfficiency[probeCodecNames[targetCodec]]
+	if targetEff == 0 {
+		return 0, false
+	}
+	return (1 - sourceEff/targetEff) * 100, true
+}
+
+// videoBitrate returns the video stream bitrate, estimating it from the
+// container bitrate when the stream does not report one.
+func videoBitrate(probe *utils.MediaProbe, stream *utils.ProbeStream) int64 {
+	if bitrate := stream.BitRateValue(); bitrate > 0 {
+		return bitrate
+	}
+
+	total := probe.BitRate()
+	for _, audio := range probe.StreamsOfType("audio") {
+		total -= audio.BitRateValue()
+	}
+	if total < 0 {
+		return 0
+	}
+	return total
+}
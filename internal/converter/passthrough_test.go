@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+func TestEstimateVideoGainUsesBitrate(t *testing.T) {
+	stream := utils.ProbeStream{CodecName: "hevc", CodecType: "video", Width: 1920, Height: 1080, AvgFrameRate: "30/1"}
+
+	// 1080p30 HEVC at ~3 Mbps is already close to the CRF 28 target
+	lowBitrate := &utils.MediaProbe{Streams: []utils.ProbeStream{stream}, Format: utils.ProbeFormat{BitRate: "3000000"}}
+	gain, ok := estimateVideoGain(lowBitrate, &lowBitrate.Streams[0], "h265", 28)
+	if !ok || gain > 0 {
+		t.Fatalf("expected no gain for low bitrate HEVC, got %.1f (ok=%v)", gain, ok)
+	}
+
+	// The same stream at 40 Mbps has plenty of room
+	highBitrate := &utils.MediaProbe{Streams: []utils.ProbeStream{stream}, Format: utils.ProbeFormat{BitRate: "40000000"}}
+	gain, ok = estimateVideoGain(highBitrate, &highBitrate.Streams[0], "h265", 28)
+	if !ok || gain < 50 {
+		t.Fatalf("expected large gain for high bitrate HEVC, got %.1f (ok=%v)", gain, ok)
+	}
+}
+
+func TestEstimateVideoGainFallsBackToCodecEfficiency(t *testing.T) {
+	probe := &utils.MediaProbe{Streams: []utils.ProbeStream{{CodecName: "hevc", CodecType: "video"}}}
+
+	gain, ok := estimateVideoGain(probe, &probe.Streams[0], "h265", 28)
+	if !ok || gain != 0 {
+		t.Fatalf("expected zero gain for HEVC to H.265 without bitrate data, got %.1f (ok=%v)", gain, ok)
+	}
+
+	probe.Streams[0].CodecName = "mpeg2video"
+	gain, ok = estimateVideoGain(probe, &probe.Streams[0], "h265", 28)
+	if !ok || gain < 50 {
+		t.Fatalf("expected large gain for MPEG-2 to H.265, got %.1f (ok=%v)", gain, ok)
+	}
+}
@@ -0,0 +1,173 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sync"
+)
+
+// processPriority holds the priority and limits applied to the encoder
+// processes. A nil processPriority starts them unchanged.
+type processPriority struct {
+	nice    int
+	ioClass string
+	ioLevel int
+	lowered bool // nice or ioClass needs a lowered thread to start from
+
+	cgroup *cgroup         // nil without limits or when unavailable
+	budget *resourceBudget // nil without limits
+
+	warnOnce sync.Once
+	warn     func(error)
+}
+
+// setupProcessPriority prepares the configured priority and limits. Limits
+// the cgroup cannot enforce are reported and left to the adaptive controller,
+// which budgets against them either way.
+func (c *Converter) setupProcessPriority() {
+	cfg := c.config.Priority
+	if !cfg.Enabled() {
+		return
+	}
+
+	p := &processPriority{
+		warn: func(err error) {
+			c.logger.Warn(fmt.Sprintf("Encoder priority not applied: %v", err))
+		},
+	}
+	if canPrioritize {
+		p.nice, p.ioClass, p.ioLevel = cfg.Nice, cfg.IOClass, cfg.IOLevel
+		p.lowered = cfg.Nice != 0 || cfg.IOClass != "none"
+		if p.lowered {
+			c.logger.Info(fmt.Sprintf("🐢 Encoders run at nice %d, I/O class %s", cfg.Nice, cfg.IOClass))
+		}
+	} else if cfg.Nice != 0 || cfg.IOClass != "none" {
+		c.logger.Warn("Encoder niceness and I/O priority are only supported on Linux, encoders run at normal priority")
+	}
+
+	if cfg.Limited() {
+		memory := int64(cfg.MemoryLimitMB) << 20
+		group, err := openCgroup(cfg.Cgroup, cfg.CPULimit, memory)
+		if err != nil {
+			c.logger.Warn(fmt.Sprintf("Encoder limits not enforced, adaptive workers still budget against them: %v", err))
+		} else {
+			c.logger.Info(fmt.Sprintf("🐢 Encoders limited to %s in %s", formatLimits(cfg.CPULimit, cfg.MemoryLimitMB), cfg.Cgroup))
+		}
+		p.cgroup = group
+		p.budget = &resourceBudget{cpus: cfg.CPULimit, memory: memory, cgroup: group}
+	}
+	c.priority = p
+}
+
+// closeProcessPriority removes the cgroup created for the run.
+func (c *Converter) closeProcessPriority() {
+	if c.priority != nil && c.priority.cgroup != nil {
+		c.priority.cgroup.close()
+	}
+}
+
+func formatLimits(cpus float64, memoryMB int) string {
+	switch {
+	case cpus > 0 && memoryMB > 0:
+		return fmt.Sprintf("%g cores and %d MB", cpus, memoryMB)
+	case cpus > 0:
+		return fmt.Sprintf("%g cores", cpus)
+	default:
+		return fmt.Sprintf("%d MB", memoryMB)
+	}
+}
+
+// startProcess starts an encoder process with the configured priority and
+// limits, and attaches it to the queued file it works on so cancelling or
+// suspending the file reaches it. The returned function detaches it once it
+// has exited. Niceness and I/O priority are per thread on Linux and
+// inherited by the processes a thread forks, so the process is started from
+// a dedicated thread that lowers its own priority first.
+func (c *Converter) startProcess(job string, cmd *exec.Cmd) (func(), error) {
+	if err := c.spawnProcess(cmd); err != nil {
+		return func() {}, err
+	}
+	return c.jobs.attach(job, cmd.Process), nil
+}
+
+func (c *Converter) spawnProcess(cmd *exec.Cmd) error {
+	p := c.priority
+	if p == nil {
+		return cmd.Start()
+	}
+	if p.cgroup != nil {
+		p.cgroup.attach(cmd)
+	}
+	if !p.lowered {
+		return cmd.Start()
+	}
+
+	started := make(chan error, 1)
+	go func() {
+		// Exiting without unlocking discards the thread, so its lowered
+		// priority never carries over to other goroutines
+		runtime.LockOSThread()
+		if err := lowerThreadPriority(p.nice, p.ioClass, p.ioLevel); err != nil {
+			p.warnOnce.Do(func() { p.warn(err) })
+		}
+		started <- cmd.Start()
+	}()
+	return <-started
+}
+
+// runProcess is exec.Cmd.Run through startProcess.
+func (c *Converter) runProcess(job string, cmd *exec.Cmd) error {
+	detach, err := c.startProcess(job, cmd)
+	if err != nil {
+		return err
+	}
+	defer detach()
+	return cmd.Wait()
+}
+
+// processOutput is exec.Cmd.CombinedOutput through startProcess.
+func (c *Converter) processOutput(job string, cmd *exec.Cmd) ([]byte, error) {
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := c.runProcess(job, cmd)
+	return output.Bytes(), err
+}
+
+// resourceBudget is the share of the machine given to the encoders. The
+// adaptive controller reads CPU and memory relative to it: the cgroup usage
+// against its limits when the cgroup is available, otherwise the system load
+// against the CPU limit.
+type resourceBudget struct {
+	cpus   float64
+	memory int64
+	cgroup *cgroup
+}
+
+// apply rescales a system snapshot to the budget, keeping the higher CPU
+// and the lower available memory reading so the controller also backs off
+// when the rest of the system is busy.
+func (b *resourceBudget) apply(snap ResourceSnapshot) ResourceSnapshot {
+	if b.cpus > 0 {
+		percent, measured := 0.0, false
+		if b.cgroup != nil {
+			percent, measured = b.cgroup.cpuPercent(b.cpus)
+		} else if snap.CPUMeasured {
+			percent, measured = snap.CPUPercent*float64(runtime.NumCPU())/b.cpus, true
+		}
+		if measured && (!snap.CPUMeasured || percent > snap.CPUPercent) {
+			snap.CPUPercent, snap.CPUMeasured = percent, true
+		}
+	}
+	if b.memory > 0 && b.cgroup != nil {
+		if used, err := b.cgroup.memoryUsage(); err == nil {
+			available := max(0, float64(b.memory-used)/float64(b.memory)*100)
+			if !snap.MemMeasured || available < snap.MemAvailablePercent {
+				snap.MemAvailablePercent, snap.MemMeasured = available, true
+			}
+		}
+	}
+	return snap
+}
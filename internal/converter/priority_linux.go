@@ -0,0 +1,191 @@
+//go:build linux
+
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// canPrioritize is true on Linux, which has per-thread niceness, ioprio and
+// cgroup v2.
+const canPrioritize = true
+
+// ioprio classes and encoding from linux/ioprio.h
+const (
+	ioprioClassRT    = 1
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// cpuPeriod is the cpu.max period in microseconds.
+const cpuPeriod = 100000
+
+// lowerThreadPriority sets the niceness and I/O priority of the calling
+// thread, which must be locked to its goroutine.
+func lowerThreadPriority(nice int, ioClass string, ioLevel int) error {
+	var errs []error
+	if nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice); err != nil {
+			errs = append(errs, fmt.Errorf("nice %d: %w", nice, err))
+		}
+	}
+
+	class := 0
+	switch ioClass {
+	case "realtime":
+		class = ioprioClassRT
+	case "best-effort":
+		class = ioprioClassBE
+	case "idle":
+		class, ioLevel = ioprioClassIdle, 0
+	}
+	if class != 0 {
+		prio := class<<ioprioClassShift | ioLevel
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(prio)); errno != 0 {
+			errs = append(errs, fmt.Errorf("I/O class %s: %w", ioClass, errno))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cgroup is a cgroup v2 directory holding the encoder processes, which are
+// started directly inside it.
+type cgroup struct {
+	path string
+	dir  *os.File
+
+	// Previous cpu.stat reading, used by the resource monitor only
+	lastUsage time.Duration
+	lastTime  time.Time
+}
+
+// openCgroup creates the cgroup and sets its limits. The parent directory
+// must be able to delegate the cpu and memory controllers: the cgroup root
+// when running as root, or a cgroup without processes of its own that the
+// user may write to.
+func openCgroup(path string, cpus float64, memory int64) (*cgroup, error) {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+		return nil, errors.New("cgroup v2 is not mounted on /sys/fs/cgroup")
+	}
+
+	// Enable the controllers the parent does not delegate yet
+	subtree := filepath.Join(filepath.Dir(path), "cgroup.subtree_control")
+	data, err := os.ReadFile(subtree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", subtree, err)
+	}
+	enabled := strings.Fields(string(data))
+	var missing []string
+	if cpus > 0 && !slices.Contains(enabled, "cpu") {
+		missing = append(missing, "+cpu")
+	}
+	if memory > 0 && !slices.Contains(enabled, "memory") {
+		missing = append(missing, "+memory")
+	}
+	if len(missing) > 0 {
+		if err := os.WriteFile(subtree, []byte(strings.Join(missing, " ")), 0); err != nil {
+			return nil, fmt.Errorf("failed to enable %s in %s: %w", strings.Join(missing, " "), subtree, err)
+		}
+	}
+	if err := os.Mkdir(path, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	// A cgroup left by an earlier run may carry other limits
+	cpuMax, memoryMax := "max", "max"
+	if cpus > 0 {
+		cpuMax = fmt.Sprintf("%d %d", max(1000, int(cpus*cpuPeriod)), cpuPeriod)
+	}
+	if memory > 0 {
+		memoryMax = strconv.FormatInt(memory, 10)
+	}
+	if err := writeCgroupLimit(path, "cpu.max", cpuMax, cpus > 0); err != nil {
+		return nil, err
+	}
+	if err := writeCgroupLimit(path, "memory.max", memoryMax, memory > 0); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	return &cgroup{path: path, dir: dir}, nil
+}
+
+// writeCgroupLimit writes a limit file. Resetting a limit whose controller
+// is not enabled is not an error.
+func writeCgroupLimit(path, name, value string, required bool) error {
+	err := os.WriteFile(filepath.Join(path, name), []byte(value), 0)
+	if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+		return fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	return nil
+}
+
+// attach makes the process start inside the cgroup (Linux 5.7 or later).
+func (g *cgroup) attach(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(g.dir.Fd())
+}
+
+// close removes the cgroup; it is kept while processes remain inside.
+func (g *cgroup) close() {
+	g.dir.Close()
+	os.Remove(g.path)
+}
+
+// cpuPercent returns the CPU used by the cgroup since the previous call, in
+// percent of the given number of cores. The first call only records the
+// reading.
+func (g *cgroup) cpuPercent(cpus float64) (float64, bool) {
+	data, err := os.ReadFile(filepath.Join(g.path, "cpu.stat"))
+	if err != nil {
+		return 0, false
+	}
+	var usage time.Duration
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			usec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			usage = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	now := time.Now()
+	lastUsage, lastTime := g.lastUsage, g.lastTime
+	g.lastUsage, g.lastTime = usage, now
+	if lastTime.IsZero() {
+		return 0, false
+	}
+	elapsed := now.Sub(lastTime)
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return float64(usage-lastUsage) / float64(elapsed) / cpus * 100, true
+}
+
+// memoryUsage returns the memory used by the cgroup in bytes.
+func (g *cgroup) memoryUsage() (int64, error) {
+	data, err := os.ReadFile(filepath.Join(g.path, "memory.current"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
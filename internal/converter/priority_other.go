@@ -0,0 +1,36 @@
+//go:build !linux
+
+package converter
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// canPrioritize is false outside Linux: encoders run at normal priority and
+// the limits are only used as the adaptive controller's budget.
+const canPrioritize = false
+
+var errNoCgroup = errors.New("cgroup limits are only supported on Linux")
+
+func lowerThreadPriority(nice int, ioClass string, ioLevel int) error {
+	return errors.New("process priority is only supported on Linux")
+}
+
+type cgroup struct{}
+
+func openCgroup(path string, cpus float64, memory int64) (*cgroup, error) {
+	return nil, errNoCgroup
+}
+
+func (g *cgroup) attach(cmd *exec.Cmd) {}
+
+func (g *cgroup) close() {}
+
+func (g *cgroup) cpuPercent(cpus float64) (float64, bool) {
+	return 0, false
+}
+
+func (g *cgroup) memoryUsage() (int64, error) {
+	return 0, errNoCgroup
+}
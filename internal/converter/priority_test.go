@@ -0,0 +1,79 @@
+package converter
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/config"
+)
+
+func TestResourceBudgetApply(t *testing.T) {
+	cpus := float64(runtime.NumCPU())
+	budget := &resourceBudget{cpus: cpus / 4}
+
+	// A quarter of the machine busy uses the whole budget
+	snap := budget.apply(ResourceSnapshot{CPUPercent: 25, CPUMeasured: true, MemAvailablePercent: 60, MemMeasured: true})
+	if snap.CPUPercent < 99.9 || snap.CPUPercent > 100.1 {
+		t.Errorf("expected 100%% of the budget, got %.1f%%", snap.CPUPercent)
+	}
+	if snap.MemAvailablePercent != 60 {
+		t.Errorf("memory without a cgroup should keep the system reading, got %.1f%%", snap.MemAvailablePercent)
+	}
+
+	// Without a CPU reading there is nothing to rescale
+	snap = budget.apply(ResourceSnapshot{})
+	if snap.CPUMeasured {
+		t.Error("CPU should stay unmeasured")
+	}
+}
+
+func TestStartProcessLowersPriority(t *testing.T) {
+	if !canPrioritize {
+		t.Skip("process priority is only supported on Linux")
+	}
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	c := &Converter{config: &config.Config{}, jobs: newJobTracker()}
+	c.priority = &processPriority{nice: 5, ioClass: "none", lowered: true, warn: func(err error) { t.Error(err) }}
+
+	cmd := exec.Command("sleep", "5")
+	detach, err := c.startProcess("", cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer detach()
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	if nice := processNice(t, strconv.Itoa(cmd.Process.Pid)); nice != 5 {
+		t.Errorf("expected the encoder at nice 5, got %d", nice)
+	}
+
+	// The thread that started the process is discarded, never reused
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if nice := processNice(t, "thread-self"); nice != 0 {
+		t.Errorf("converter thread priority changed to nice %d", nice)
+	}
+}
+
+// processNice reads the niceness from /proc/<pid>/stat.
+func processNice(t *testing.T, pid string) int {
+	data, err := os.ReadFile("/proc/" + pid + "/stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fields after the parenthesised command name start at the state (3rd)
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	nice, err := strconv.Atoi(fields[16]) // 19th field
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nice
+}
@@ -0,0 +1,134 @@
+package converter
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// progressArgs make ffmpeg write its machine-readable progress to stdout,
+// which is free since outputs always go to files, and silence the human
+// stats line on stderr.
+var progressArgs = []string{"-progress", "pipe:1", "-nostats"}
+
+// VideoProgress is a snapshot of a running video encode, parsed from the
+// ffmpeg -progress stream.
+type VideoProgress struct {
+	Job        string // queued file the encode belongs to
+	File       string // name shown in logs
+	Source     string // path of the encoded input
+	SourceSize int64
+	Pass       int
+	Passes     int
+	Started    time.Time // start of the first pass
+	Encoder    string
+
+	// Duration of the input from ffprobe, 0 when unknown
+	Duration time.Duration
+
+	OutTime   time.Duration // out_time_us
+	Frame     int64
+	FPS       float64
+	Bitrate   float64 // kbit/s
+	TotalSize int64   // bytes written so far
+	Speed     float64
+	Done      bool // last update of the pass
+}
+
+// Percent returns the overall progress, across every pass of a multi-pass
+// encode, or 0 when the duration is unknown.
+func (p VideoProgress) Percent() float64 {
+	if p.Duration <= 0 {
+		return 0
+	}
+	percent := float64(p.OutTime) / float64(p.Duration) * 100
+	if p.Done || percent > 100 {
+		percent = 100
+	}
+	if p.Passes > 1 {
+		percent = (float64(p.Pass-1)*100 + percent) / float64(p.Passes)
+	}
+	return percent
+}
+
+// ETA extrapolates the remaining time from the elapsed time, once enough of
+// the encode is done for the estimate to settle.
+func (p VideoProgress) ETA() (time.Duration, bool) {
+	percent := p.Percent()
+	if p.Speed <= 0 || percent <= 5 {
+		return 0, false
+	}
+	elapsed := time.Since(p.Started)
+	remaining := time.Duration(float64(elapsed)/(percent/100)) - elapsed
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, true
+}
+
+// readProgress parses ffmpeg's key=value progress stream and emits a snapshot
+// at the end of each block (a progress=continue or progress=end line). N/A
+// values leave the previous value in place.
+func readProgress(reader io.Reader, progress VideoProgress, emit func(VideoProgress)) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "out_time_us":
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				progress.OutTime = time.Duration(us) * time.Microsecond
+			}
+		case "frame":
+			if frame, err := strconv.ParseInt(value, 10, 64); err == nil {
+				progress.Frame = frame
+			}
+		case "fps":
+			if fps, err := strconv.ParseFloat(value, 64); err == nil {
+				progress.FPS = fps
+			}
+		case "bitrate":
+			if kbps, err := strconv.ParseFloat(strings.TrimSuffix(value, "kbits/s"), 64); err == nil {
+				progress.Bitrate = kbps
+			}
+		case "total_size":
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+				progress.TotalSize = size
+			}
+		case "speed":
+			if speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+				progress.Speed = speed
+			}
+		case "progress":
+			progress.Done = value == "end"
+			emit(progress)
+			if progress.Done {
+				return
+			}
+		}
+	}
+}
+
+// OnVideoProgress registers a callback receiving every progress snapshot of
+// the running video encodes. Callbacks are called from the encoding
+// goroutines and must return quickly.
+func (c *Converter) OnVideoProgress(fn func(VideoProgress)) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+	c.progressSubscribers = append(c.progressSubscribers, fn)
+}
+
+// publishProgress hands a snapshot to every subscriber.
+func (c *Converter) publishProgress(progress VideoProgress) {
+	c.progressMu.RLock()
+	defer c.progressMu.RUnlock()
+	for _, fn := range c.progressSubscribers {
+		fn(progress)
+	}
+}
@@ -0,0 +1,67 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadProgress(t *testing.T) {
+	stream := `frame=120
+fps=59.94
+stream_0_0_q=28.0
+bitrate=N/A
+total_size=48
+out_time_us=N/A
+speed=N/A
+progress=continue
+frame=300
+fps=60.00
+stream_0_0_q=28.0
+bitrate=2048.5kbits/s
+total_size=1310720
+out_time_us=5005000
+out_time=00:00:05.005000
+speed=2.5x
+progress=end
+`
+	var snapshots []VideoProgress
+	readProgress(strings.NewReader(stream), VideoProgress{Duration: 10 * time.Second, Pass: 1, Passes: 1}, func(p VideoProgress) {
+		snapshots = append(snapshots, p)
+	})
+
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snapshots))
+	}
+	first := snapshots[0]
+	if first.Frame != 120 || first.OutTime != 0 || first.Speed != 0 || first.Done {
+		t.Errorf("unexpected first snapshot: %+v", first)
+	}
+	last := snapshots[1]
+	if last.OutTime != 5005*time.Millisecond || last.TotalSize != 1310720 || last.Bitrate != 2048.5 || last.Speed != 2.5 || last.FPS != 60 || !last.Done {
+		t.Errorf("unexpected last snapshot: %+v", last)
+	}
+}
+
+func TestVideoProgressPercent(t *testing.T) {
+	progress := VideoProgress{Duration: 10 * time.Second, OutTime: 2500 * time.Millisecond, Pass: 1, Passes: 1}
+	if got := progress.Percent(); got != 25 {
+		t.Errorf("expected 25%%, got %.2f", got)
+	}
+
+	// Second pass of two, halfway through
+	progress = VideoProgress{Duration: 10 * time.Second, OutTime: 5 * time.Second, Pass: 2, Passes: 2}
+	if got := progress.Percent(); got != 75 {
+		t.Errorf("expected 75%%, got %.2f", got)
+	}
+
+	// The last block reports completion even when out_time falls short
+	progress = VideoProgress{Duration: 10 * time.Second, OutTime: 9960 * time.Millisecond, Pass: 1, Passes: 1, Done: true}
+	if got := progress.Percent(); got != 100 {
+		t.Errorf("expected 100%%, got %.2f", got)
+	}
+
+	if got := (VideoProgress{OutTime: time.Second}).Percent(); got != 0 {
+		t.Errorf("expected 0%% without a duration, got %.2f", got)
+	}
+}
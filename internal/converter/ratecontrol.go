@@ -0,0 +1,111 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// Container overhead reserved when deriving a bitrate from a target size.
+const containerOverheadRatio = 0.02
+
+// minTwoPassBitrate rejects target sizes too small for a watchable video.
+const minTwoPassBitrate = 150_000
+
+// softwareRateControl returns the rate control arguments of a software encoder
+// for the configured video mode, a short description for logs and whether the
+// encode needs two passes.
+func (c *Converter) softwareRateControl(codec string, crf int, inputPath string, audioBitrate int64) ([]string, string, bool, error) {
+	switch c.config.VideoMode {
+	case "cq":
+		maxrate, err := utils.ParseBitrate(c.config.VideoMaxBitrate)
+		if err != nil {
+			return nil, "", false, err
+		}
+		if codec == "librav1e" {
+			// rav1e is either quantizer or bitrate driven, it cannot cap a quantizer
+			c.warnOnce("cq:librav1e", "📹 librav1e cannot cap the bitrate in cq mode, encoding at constant quantizer")
+			q := rav1eQuantizer(crf)
+			return []string{"-qp", strconv.Itoa(q)}, fmt.Sprintf("QP %d", q), false, nil
+		}
+		if codec == "libaom-av1" {
+			// libaom switches to constrained quality when both are set
+			return []string{"-crf", strconv.Itoa(crf), "-b:v", utils.FormatBitrate(maxrate)},
+				fmt.Sprintf("CQ %d, max %s", crf, utils.FormatBitrate(maxrate)), false, nil
+		}
+		return []string{
+				"-crf", strconv.Itoa(crf),
+				"-maxrate", utils.FormatBitrate(maxrate),
+				"-bufsize", utils.FormatBitrate(2 * maxrate),
+			},
+			fmt.Sprintf("CRF %d, max %s", crf, utils.FormatBitrate(maxrate)), false, nil
+	case "2pass":
+		bitrate, err := c.twoPassBitrate(inputPath, audioBitrate)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return []string{"-b:v", utils.FormatBitrate(bitrate)},
+			fmt.Sprintf("2-pass %s", utils.FormatBitrate(bitrate)), true, nil
+	default:
+		if codec == "librav1e" {
+			q := rav1eQuantizer(crf)
+			return []string{"-qp", strconv.Itoa(q)}, fmt.Sprintf("QP %d", q), false, nil
+		}
+		args := []string{"-crf", strconv.Itoa(crf)}
+		if codec == "libaom-av1" {
+			args = append(args, "-b:v", "0")
+		}
+		return args, fmt.Sprintf("CRF %d", crf), false, nil
+	}
+}
+
+// twoPassBitrate returns the configured video bitrate, or derives it from the
+// target size, the duration and the audio tracks sharing the budget.
+func (c *Converter) twoPassBitrate(inputPath string, audioBitrate int64) (int64, error) {
+	if c.config.VideoTargetSizeMB <= 0 {
+		return utils.ParseBitrate(c.config.VideoTargetBitrate)
+	}
+
+	duration, err := utils.GetVideoDuration(inputPath)
+	if err != nil {
+		return 0, fmt.Errorf("target size needs the video duration: %w", err)
+	}
+
+	totalBits := c.config.VideoTargetSizeMB * 1024 * 1024 * 8 * (1 - containerOverheadRatio)
+	bitrate := int64(totalBits/duration.Seconds()) - audioBitrate
+	if bitrate < minTwoPassBitrate {
+		return 0, fmt.Errorf("target size %.1f MB is too small for %s of video", c.config.VideoTargetSizeMB, c.formatDuration(duration))
+	}
+	return bitrate, nil
+}
+
+// passArgs returns the ffmpeg arguments and x265 parameters selecting one pass
+// of a two-pass encode. Statistics are written next to the temporary output.
+func passArgs(codec string, pass int, logPrefix string) ([]string, []string) {
+	if codec == "libx265" {
+		return nil, []string{
+			"pass=" + strconv.Itoa(pass),
+			"stats=" + escapeX265Value(logPrefix+"-0.log"),
+		}
+	}
+	return []string{"-pass", strconv.Itoa(pass), "-passlogfile", logPrefix}, nil
+}
+
+// escapeX265Value escapes separators in a -x265-params value (Windows drive
+// letters contain a colon).
+func escapeX265Value(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, ":", `\:`)
+}
+
+// removePassLogs deletes the statistics files of a two-pass encode.
+func removePassLogs(logPrefix string) {
+	matches, _ := filepath.Glob(logPrefix + "*")
+	for _, match := range matches {
+		os.Remove(match)
+	}
+}
@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/config"
+)
+
+func TestSoftwareRateControlModes(t *testing.T) {
+	c := &Converter{config: &config.Config{VideoMode: "cq", VideoMaxBitrate: "8M"}}
+
+	args, _, twoPass, err := c.softwareRateControl("libx265", 24, "clip.mov", 0)
+	if err != nil || twoPass {
+		t.Fatalf("unexpected cq result: %v (two-pass %v)", err, twoPass)
+	}
+	if got := strings.Join(args, " "); got != "-crf 24 -maxrate 8000k -bufsize 16000k" {
+		t.Fatalf("unexpected cq arguments: %q", got)
+	}
+
+	c.config.VideoMode = "2pass"
+	c.config.VideoTargetBitrate = "2500k"
+	args, _, twoPass, err = c.softwareRateControl("libx264", 24, "clip.mov", 0)
+	if err != nil || !twoPass || strings.Join(args, " ") != "-b:v 2500k" {
+		t.Fatalf("unexpected 2pass result: %v %v %v", args, twoPass, err)
+	}
+}
+
+func TestPassArgsUseEncoderSpecificOptions(t *testing.T) {
+	args, params := passArgs("libx264", 1, "/out/clip.mp4.passlog")
+	if strings.Join(args, " ") != "-pass 1 -passlogfile /out/clip.mp4.passlog" || params != nil {
+		t.Fatalf("unexpected libx264 pass arguments: %v %v", args, params)
+	}
+
+	// x265 takes its statistics file through -x265-params, with separators escaped
+	args, params = passArgs("libx265", 2, `C:\out\clip.mp4.passlog`)
+	if args != nil || strings.Join(params, ":") != `pass=2:stats=C\:\\out\\clip.mp4.passlog-0.log` {
+		t.Fatalf("unexpected libx265 pass parameters: %v %v", args, params)
+	}
+}
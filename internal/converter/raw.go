@@ -0,0 +1,231 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// rawFormats lists the camera RAW extensions handled by the development step.
+var rawFormats = []string{"cr2", "arw", "nef", "dng", "raw"}
+
+// rawBackendCommands maps each RAW backend to the executable it requires.
+var rawBackendCommands = map[string]string{
+	"dcraw_emu":   "dcraw_emu",
+	"darktable":   "darktable-cli",
+	"rawtherapee": "rawtherapee-cli",
+	"embedded":    "exiftool",
+}
+
+// Output colour spaces understood by each backend. Profiles missing from a
+// table fall back to sRGB with a warning at startup.
+var (
+	dcrawOutputProfiles = map[string]string{
+		"srgb":     "1",
+		"adobe":    "2",
+		"prophoto": "4", // "7" would be DCI-P3, not Display P3
+	}
+	darktableOutputProfiles = map[string]string{
+		"srgb":  "SRGB",
+		"adobe": "ADOBERGB",
+	}
+	rawtherapeeOutputProfiles = map[string]string{
+		"srgb":     "RTv4_sRGB",
+		"adobe":    "RTv4_Medium",
+		"prophoto": "RTv4_Large",
+	}
+)
+
+// minEmbeddedPreviewSize rejects the thumbnail-sized previews some cameras
+// store in place of a full-size JPEG.
+const minEmbeddedPreviewSize = 64 * 1024
+
+// checkRawBackend verifies that the configured RAW backend is installed and
+// warns about options it cannot honour.
+func (c *Converter) checkRawBackend() error {
+	raw := c.config.Raw
+	command, ok := rawBackendCommands[raw.Backend]
+	if !ok {
+		return nil
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return fmt.Errorf("RAW backend %s requires %s, which was not found in PATH", raw.Backend, command)
+	}
+
+	// Developed files carry little or none of the RAW's EXIF and GPS metadata
+	if _, err := exec.LookPath("exiftool"); err != nil {
+		c.logger.Warn(fmt.Sprintf("exiftool not found: photos developed with %s lose their EXIF and GPS metadata", raw.Backend))
+	}
+
+	developAdjusted := raw.WhiteBalance != "camera" || raw.Exposure != 0
+	switch raw.Backend {
+	case "dcraw_emu":
+		if _, ok := dcrawOutputProfiles[raw.ColorProfile]; !ok {
+			c.logger.Warn(fmt.Sprintf("dcraw_emu backend cannot export %s, using srgb", raw.ColorProfile))
+		}
+	case "darktable":
+		if developAdjusted {
+			c.logger.Warn("darktable backend ignores RAW white balance and exposure options (use a darktable style instead)")
+		}
+		if _, ok := darktableOutputProfiles[raw.ColorProfile]; !ok {
+			c.logger.Warn(fmt.Sprintf("darktable backend cannot export %s, using srgb", raw.ColorProfile))
+		}
+	case "rawtherapee":
+		if _, ok := rawtherapeeOutputProfiles[raw.ColorProfile]; !ok {
+			c.logger.Warn(fmt.Sprintf("rawtherapee backend cannot export %s, using srgb", raw.ColorProfile))
+		}
+	case "embedded":
+		if developAdjusted || raw.ColorProfile != "srgb" {
+			c.logger.Warn("embedded RAW previews are already rendered: white balance, exposure and colour profile options are ignored")
+		}
+	}
+
+	c.logger.Info(fmt.Sprintf("RAW development: %s (white balance %s, exposure %+.1f EV, %s)",
+		raw.Backend, raw.WhiteBalance, raw.Exposure, raw.ColorProfile))
+	return nil
+}
+
+// developRaw renders a RAW source into an intermediate file next to the output
+// and returns its path together with the method used. With the magick backend
+// the source is returned unchanged. The caller removes the intermediate file.
+func (c *Converter) developRaw(ctx context.Context, inputPath, outputPath string) (string, string, error) {
+	backend := c.config.Raw.Backend
+	developed := outputPath + ".develop.tif"
+	var err error
+	switch backend {
+	case "dcraw_emu":
+		err = c.runRawCommand(ctx, inputPath, "dcraw_emu", c.dcrawArgs(inputPath, developed)...)
+	case "darktable":
+		os.Remove(developed) // darktable-cli never overwrites, it renames instead
+		err = c.runRawCommand(ctx, inputPath, "darktable-cli", c.darktableArgs(inputPath, developed)...)
+	case "rawtherapee":
+		profilePath := outputPath + ".develop.pp3"
+		if err := os.WriteFile(profilePath, []byte(c.rawtherapeeProfile()), 0644); err != nil {
+			return "", backend, fmt.Errorf("failed to write RawTherapee profile: %w", err)
+		}
+		defer os.Remove(profilePath)
+		err = c.runRawCommand(ctx, inputPath, "rawtherapee-cli",
+			"-o", developed, "-t", "-b16", "-Y", "-d", "-p", profilePath, "-c", inputPath)
+	case "embedded":
+		developed = outputPath + ".develop.jpg"
+		err = c.extractEmbeddedJPEG(ctx, inputPath, developed)
+	default:
+		return inputPath, "magick", nil
+	}
+	if err != nil {
+		return developed, backend, err
+	}
+	return developed, backend, c.copyRawMetadata(ctx, inputPath, developed)
+}
+
+// copyRawMetadata copies the EXIF, GPS and XMP metadata of the RAW file onto
+// the developed intermediate, from which ImageMagick carries it into the
+// output. The developers already rotate the pixels upright, so only the
+// embedded preview keeps the RAW orientation tag.
+func (c *Converter) copyRawMetadata(ctx context.Context, inputPath, developed string) error {
+	if _, err := exec.LookPath("exiftool"); err != nil {
+		return nil // reported at startup
+	}
+	args := []string{"-q", "-overwrite_original", "-TagsFromFile", inputPath, "-all:all"}
+	if c.config.Raw.Backend != "embedded" {
+		args = append(args, "--Orientation")
+	}
+	return c.runRawCommand(ctx, inputPath, "exiftool", append(args, developed)...)
+}
+
+func (c *Converter) dcrawArgs(inputPath, developed string) []string {
+	args := []string{"-T", "-6"}
+	if c.config.Raw.WhiteBalance == "auto" {
+		args = append(args, "-a")
+	} else {
+		args = append(args, "-w")
+	}
+	profile, ok := dcrawOutputProfiles[c.config.Raw.ColorProfile]
+	if !ok {
+		profile = dcrawOutputProfiles["srgb"]
+	}
+	args = append(args, "-o", profile)
+	if c.config.Raw.Exposure != 0 {
+		// LibRaw takes a linear shift limited to -2..+3 EV, preserving highlights
+		shift := math.Pow(2, math.Max(-2, math.Min(3, c.config.Raw.Exposure)))
+		args = append(args, "-aexpo", fmt.Sprintf("%.3f", shift), "1")
+	}
+	return append(args, "-Z", developed, inputPath)
+}
+
+func (c *Converter) darktableArgs(inputPath, developed string) []string {
+	profile, ok := darktableOutputProfiles[c.config.Raw.ColorProfile]
+	if !ok {
+		profile = darktableOutputProfiles["srgb"]
+	}
+	return []string{
+		inputPath, developed,
+		"--icc-type", profile,
+		"--core", "--conf", "plugins/imageio/format/tiff/bpp=16",
+	}
+}
+
+// rawtherapeeProfile builds a partial processing profile applied on top of
+// RawTherapee's default RAW profile.
+func (c *Converter) rawtherapeeProfile() string {
+	whiteBalance := "Camera"
+	if c.config.Raw.WhiteBalance == "auto" {
+		whiteBalance = "autold"
+	}
+	profile, ok := rawtherapeeOutputProfiles[c.config.Raw.ColorProfile]
+	if !ok {
+		profile = rawtherapeeOutputProfiles["srgb"]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Exposure]\nCompensation=%.2f\n\n", c.config.Raw.Exposure)
+	fmt.Fprintf(&b, "[White Balance]\nEnabled=true\nSetting=%s\n\n", whiteBalance)
+	fmt.Fprintf(&b, "[Color Management]\nOutputProfile=%s\n", profile)
+	return b.String()
+}
+
+// extractEmbeddedJPEG writes the full-size JPEG stored inside the RAW file.
+func (c *Converter) extractEmbeddedJPEG(ctx context.Context, inputPath, developed string) error {
+	var preview []byte
+	for _, tag := range []string{"-JpgFromRaw", "-PreviewImage"} {
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, "exiftool", "-b", tag, inputPath)
+		cmd.Stdout = &output
+		if err := c.runProcess(inputPath, cmd); err == nil && output.Len() >= minEmbeddedPreviewSize {
+			preview = output.Bytes()
+			break
+		}
+	}
+	if preview == nil {
+		return fmt.Errorf("no full-size embedded JPEG found")
+	}
+
+	if err := os.WriteFile(developed, preview, 0644); err != nil {
+		return fmt.Errorf("failed to write embedded JPEG: %w", err)
+	}
+	return nil
+}
+
+func (c *Converter) runRawCommand(ctx context.Context, job, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderrBuf strings.Builder
+	cmd.Stderr = &stderrBuf
+	if err := c.runProcess(job, cmd); err != nil {
+		if stderrOutput := strings.TrimSpace(stderrBuf.String()); stderrOutput != "" {
+			return fmt.Errorf("%s failed: %w - %s", name, err, stderrOutput)
+		}
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
+
+// isRawSource reports whether a file goes through the RAW development step.
+func isRawSource(inputPath string) bool {
+	return utils.HasExtension(inputPath, rawFormats)
+}
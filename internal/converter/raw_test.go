@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/config"
+)
+
+func TestDcrawArgsMapDevelopOptions(t *testing.T) {
+	c := &Converter{config: &config.Config{Raw: config.RawConfig{
+		Backend:      "dcraw_emu",
+		WhiteBalance: "auto",
+		Exposure:     1,
+		ColorProfile: "adobe",
+	}}}
+
+	args := strings.Join(c.dcrawArgs("in.cr2", "out.develop.tif"), " ")
+	for _, want := range []string{"-a", "-o 2", "-aexpo 2.000 1", "-Z out.develop.tif in.cr2"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("expected %q in dcraw_emu arguments, got %q", want, args)
+		}
+	}
+
+	// Exposure is clamped to the range LibRaw accepts
+	c.config.Raw.Exposure = 5
+	args = strings.Join(c.dcrawArgs("in.cr2", "out.develop.tif"), " ")
+	if !strings.Contains(args, "-aexpo 8.000 1") {
+		t.Fatalf("expected clamped exposure shift, got %q", args)
+	}
+}
+
+func TestDcrawArgsFallBackFromP3(t *testing.T) {
+	c := &Converter{config: &config.Config{Raw: config.RawConfig{Backend: "dcraw_emu", WhiteBalance: "camera", ColorProfile: "p3"}}}
+
+	args := strings.Join(c.dcrawArgs("in.nef", "out.develop.tif"), " ")
+	if !strings.Contains(args, "-o 1") {
+		t.Fatalf("expected p3 to fall back to sRGB (-o 1), got %q", args)
+	}
+}
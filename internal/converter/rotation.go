@@ -0,0 +1,44 @@
+package converter
+
+// rotationFilter returns the filter turning frames upright for a clockwise
+// display rotation, or an empty string when none is needed.
+func rotationFilter(rotation int) string {
+	switch rotation {
+	case 90:
+		return "transpose=clock"
+	case 180:
+		return "hflip,vflip"
+	case 270:
+		return "transpose=cclock"
+	default:
+		return ""
+	}
+}
+
+// rotationSettings describes how a rotated source is encoded. Autorotation
+// is always disabled so software decoding, hardware decoding and segment
+// encodes handle the display matrix the same way.
+type rotationSettings struct {
+	Rotation int
+	// Filter bakes the rotation into the pixels; without it the source
+	// display matrix is carried into the output
+	Filter string
+	// InputArgs reset the source display matrix when the rotation is baked,
+	// so the output is not rotated a second time by players
+	InputArgs []string
+}
+
+// planRotation applies the rotation mode to a source display rotation.
+func planRotation(mode string, rotation int) rotationSettings {
+	settings := rotationSettings{Rotation: rotation}
+	if mode == "bake" && rotation != 0 {
+		settings.Filter = rotationFilter(rotation)
+		settings.InputArgs = []string{"-display_rotation:v:0", "0"}
+	}
+	return settings
+}
+
+// KeepsMatrix reports whether the output relies on a display matrix.
+func (r rotationSettings) KeepsMatrix() bool {
+	return r.Rotation != 0 && r.Filter == ""
+}
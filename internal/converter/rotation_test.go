@@ -0,0 +1,32 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanRotation(t *testing.T) {
+	tests := []struct {
+		mode     string
+		rotation int
+		expected rotationSettings
+		matrix   bool
+	}{
+		{"bake", 0, rotationSettings{}, false},
+		{"bake", 90, rotationSettings{Rotation: 90, Filter: "transpose=clock", InputArgs: []string{"-display_rotation:v:0", "0"}}, false},
+		{"bake", 180, rotationSettings{Rotation: 180, Filter: "hflip,vflip", InputArgs: []string{"-display_rotation:v:0", "0"}}, false},
+		{"bake", 270, rotationSettings{Rotation: 270, Filter: "transpose=cclock", InputArgs: []string{"-display_rotation:v:0", "0"}}, false},
+		{"metadata", 90, rotationSettings{Rotation: 90}, true},
+		{"metadata", 0, rotationSettings{}, false},
+	}
+
+	for _, tt := range tests {
+		settings := planRotation(tt.mode, tt.rotation)
+		if !reflect.DeepEqual(settings, tt.expected) {
+			t.Errorf("%s %d°: expected %+v, got %+v", tt.mode, tt.rotation, tt.expected, settings)
+		}
+		if settings.KeepsMatrix() != tt.matrix {
+			t.Errorf("%s %d°: KeepsMatrix() = %v", tt.mode, tt.rotation, settings.KeepsMatrix())
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package converter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kevindurb/media-converter/internal/config"
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// conversionPlan describes how a single source file is handled once the
+// routing rules have been evaluated.
+type conversionPlan struct {
+	Action    string // convert, copy or skip
+	MediaType string // pipeline used for conversion: photo or video
+	Format    string // photo output format
+	Codec     string // video codec
+	Quality   int    // photo quality or video CRF
+	Lossless  bool
+	RuleName  string
+
+	// Animation is set for multi-frame image sources
+	Animation *utils.AnimationInfo
+	Flatten   bool
+
+	// Chapters is set for videos belonging to a chaptered recording
+	Chapters *chapterJob
+}
+
+// defaultPlan returns the plan derived from the global settings.
+func (c *Converter) defaultPlan(fileType string) conversionPlan {
+	plan := conversionPlan{
+		Action:    config.ActionConvert,
+		MediaType: fileType,
+		Format:    c.config.PhotoFormat,
+		Codec:     c.config.VideoCodec,
+	}
+
+	if fileType == "video" {
+		plan.Quality = c.config.VideoCRF
+	} else {
+		plan.Quality = c.photoQuality(plan.Format)
+	}
+
+	return plan
+}
+
+func (c *Converter) photoQuality(format string) int {
+	if format == "webp" {
+		return c.config.PhotoQualityWebP
+	}
+	return c.config.PhotoQualityAVIF
+}
+
+// planConversion evaluates the routing rules for a source file. The first
+// matching rule wins; files without a match use the global settings.
+func (c *Converter) planConversion(inputPath, fileType string) conversionPlan {
+	plan := c.defaultPlan(fileType)
+	if len(c.config.Rules) == 0 {
+		return plan
+	}
+
+	var (
+		attrs      utils.MediaAttributes
+		attrsErr   error
+		attrsReady bool
+	)
+
+	for _, rule := range c.config.Rules {
+		if rule.Match.NeedsAttributes() && !attrsReady {
+			if fileType == "video" {
+				attrs, attrsErr = utils.GetVideoAttributes(inputPath)
+			} else {
+				attrs, attrsErr = utils.GetImageAttributes(inputPath)
+			}
+			attrsReady = true
+			if attrsErr != nil {
+				c.logger.Warn(fmt.Sprintf("Routing rules: unable to inspect %s (%v)", filepath.Base(inputPath), attrsErr))
+			}
+		}
+
+		if rule.Match.NeedsAttributes() && attrsErr != nil {
+			continue
+		}
+
+		if !c.ruleMatches(rule.Match, inputPath, attrs) {
+			continue
+		}
+
+		return c.applyRule(plan, rule)
+	}
+
+	return plan
+}
+
+func (c *Converter) applyRule(plan conversionPlan, rule config.RoutingRule) conversionPlan {
+	plan.Action = rule.Action
+	plan.RuleName = rule.Name
+	plan.Lossless = rule.Lossless
+
+	if plan.MediaType == "photo" && rule.Codec != "" {
+		// Photo routed to the video pipeline (e.g. animated GIF to H.265)
+		plan.MediaType = "video"
+		plan.Codec = rule.Codec
+		plan.Quality = c.config.VideoCRF
+	} else if plan.MediaType == "video" && rule.Codec != "" {
+		plan.Codec = rule.Codec
+	}
+
+	if rule.Format != "" {
+		plan.Format = rule.Format
+		if plan.MediaType == "photo" {
+			plan.Quality = c.photoQuality(plan.Format)
+		}
+	}
+
+	if rule.Quality > 0 {
+		plan.Quality = rule.Quality
+	}
+
+	return plan
+}
+
+func (c *Converter) ruleMatches(match config.RuleMatch, inputPath string, attrs utils.MediaAttributes) bool {
+	if len(match.Extensions) > 0 && !utils.HasExtension(inputPath, match.Extensions) {
+		return false
+	}
+
+	if match.PathGlob != "" && !matchPathGlob(match.PathGlob, c.config.SourceDir, inputPath) {
+		return false
+	}
+
+	if match.CameraModel != "" {
+		if ok, _ := filepath.Match(strings.ToLower(match.CameraModel), strings.ToLower(attrs.CameraModel)); !ok {
+			return false
+		}
+	}
+
+	if !withinRange(attrs.Width, match.MinWidth, match.MaxWidth) ||
+		!withinRange(attrs.Height, match.MinHeight, match.MaxHeight) ||
+		!withinRange(attrs.BitDepth, match.MinBitDepth, match.MaxBitDepth) {
+		return false
+	}
+
+	if match.Alpha != nil && *match.Alpha != attrs.HasAlpha {
+		return false
+	}
+
+	if match.Animated != nil && *match.Animated != attrs.Animated() {
+		return false
+	}
+
+	return true
+}
+
+// matchPathGlob matches patterns without a separator against the file name and
+// other patterns against the path relative to the source directory.
+func matchPathGlob(pattern, sourceDir, inputPath string) bool {
+	target := filepath.Base(inputPath)
+	if strings.ContainsRune(pattern, '/') || strings.ContainsRune(pattern, filepath.Separator) {
+		rel, err := filepath.Rel(sourceDir, inputPath)
+		if err != nil {
+			rel = inputPath
+		}
+		target = filepath.ToSlash(rel)
+		pattern = filepath.ToSlash(pattern)
+	}
+
+	ok, err := filepath.Match(strings.ToLower(pattern), strings.ToLower(target))
+	return err == nil && ok
+}
+
+func withinRange(value, minVal, maxVal int) bool {
+	if minVal > 0 && value < minVal {
+		return false
+	}
+	if maxVal > 0 && value > maxVal {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package converter
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/config"
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+func newRulesTestConverter(rules []config.RoutingRule) *Converter {
+	return &Converter{
+		config: &config.Config{
+			SourceDir:        "/library",
+			PhotoFormat:      "avif",
+			PhotoQualityAVIF: 80,
+			PhotoQualityWebP: 85,
+			VideoCodec:       "h265",
+			VideoCRF:         28,
+			Rules:            rules,
+		},
+	}
+}
+
+func TestPlanConversionFirstMatchWins(t *testing.T) {
+	c := newRulesTestConverter([]config.RoutingRule{
+		{Name: "screenshots", Action: config.ActionConvert, Format: "webp", Lossless: true, Match: config.RuleMatch{Extensions: []string{"png"}}},
+		{Name: "raw masters", Action: config.ActionCopy, Match: config.RuleMatch{Extensions: []string{"cr2", "nef"}}},
+		{Name: "all pngs", Action: config.ActionSkip, Match: config.RuleMatch{Extensions: []string{"png"}}},
+	})
+
+	plan := c.planConversion("/library/shot.PNG", "photo")
+	if plan.RuleName != "screenshots" || plan.Format != "webp" || !plan.Lossless || plan.Quality != 85 {
+		t.Fatalf("unexpected plan for png: %+v", plan)
+	}
+
+	plan = c.planConversion("/library/raw/img.cr2", "photo")
+	if plan.Action != config.ActionCopy {
+		t.Fatalf("expected RAW to be copied, got %+v", plan)
+	}
+
+	plan = c.planConversion("/library/holiday.jpg", "photo")
+	if plan.RuleName != "" || plan.Format != "avif" || plan.Quality != 80 {
+		t.Fatalf("expected default plan for jpg, got %+v", plan)
+	}
+}
+
+func TestPlanConversionRoutesPhotoToVideo(t *testing.T) {
+	c := newRulesTestConverter([]config.RoutingRule{
+		{Name: "gif to video", Action: config.ActionConvert, Codec: "h265", Quality: 30, Match: config.RuleMatch{Extensions: []string{"gif"}}},
+	})
+
+	plan := c.planConversion("/library/funny.gif", "photo")
+	if plan.MediaType != "video" || plan.Codec != "h265" || plan.Quality != 30 {
+		t.Fatalf("expected gif to be routed to video pipeline, got %+v", plan)
+	}
+}
+
+func TestRuleMatchesAttributesAndGlobs(t *testing.T) {
+	c := newRulesTestConverter(nil)
+	yes := true
+	attrs := utils.MediaAttributes{Width: 4000, Height: 3000, BitDepth: 8, CameraModel: "Canon EOS R6", Frames: 1}
+
+	cases := []struct {
+		name  string
+		match config.RuleMatch
+		path  string
+		want  bool
+	}{
+		{"camera glob", config.RuleMatch{CameraModel: "canon*"}, "/library/a.jpg", true},
+		{"camera mismatch", config.RuleMatch{CameraModel: "iphone*"}, "/library/a.jpg", false},
+		{"min width", config.RuleMatch{MinWidth: 3000}, "/library/a.jpg", true},
+		{"max height", config.RuleMatch{MaxHeight: 2000}, "/library/a.jpg", false},
+		{"needs alpha", config.RuleMatch{Alpha: &yes}, "/library/a.jpg", false},
+		{"needs animation", config.RuleMatch{Animated: &yes}, "/library/a.jpg", false},
+		{"name glob", config.RuleMatch{PathGlob: "Screenshot*"}, "/library/2024/screenshot 1.png", true},
+		{"relative glob", config.RuleMatch{PathGlob: "scans/*"}, filepath.Join("/library", "scans", "page.tif"), true},
+		{"relative glob miss", config.RuleMatch{PathGlob: "scans/*"}, filepath.Join("/library", "other", "page.tif"), false},
+	}
+
+	for _, tc := range cases {
+		if got := c.ruleMatches(tc.match, tc.path, attrs); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/config"
+)
+
+// scheduleCheckInterval is how often the run windows are re-evaluated.
+const scheduleCheckInterval = 30 * time.Second
+
+// schedule holds the parsed run windows: the default ones and the weekday
+// overrides.
+type schedule struct {
+	windows  []config.RunWindow
+	weekdays map[time.Weekday][]config.RunWindow
+}
+
+// newSchedule parses the validated schedule configuration.
+func newSchedule(cfg config.ScheduleConfig) *schedule {
+	s := &schedule{weekdays: make(map[time.Weekday][]config.RunWindow)}
+	for _, value := range cfg.RunWindows {
+		if window, err := config.ParseRunWindow(value); err == nil {
+			s.windows = append(s.windows, window)
+		}
+	}
+	for name, values := range cfg.Weekdays {
+		day, ok := config.ParseWeekday(name)
+		if !ok {
+			continue
+		}
+		windows := []config.RunWindow{}
+		for _, value := range values {
+			if window, err := config.ParseRunWindow(value); err == nil {
+				windows = append(windows, window)
+			}
+		}
+		s.weekdays[day] = windows
+	}
+	return s
+}
+
+// windowsOn returns the windows of a weekday. Days without an override use
+// the default windows, or are open all day when there are none.
+func (s *schedule) windowsOn(day time.Weekday) []config.RunWindow {
+	if windows, ok := s.weekdays[day]; ok {
+		return windows
+	}
+	if len(s.windows) == 0 {
+		return []config.RunWindow{{Start: 0, End: 24 * time.Hour}}
+	}
+	return s.windows
+}
+
+// at returns the window open at a given local time. A window running past
+// midnight belongs to the day it starts: "22:00-07:00" on Friday covers
+// Saturday until 07:00, whatever Saturday's own windows are.
+func (s *schedule) at(now time.Time) (config.RunWindow, bool) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	clock := now.Sub(midnight)
+
+	for _, window := range s.windowsOn(now.Weekday()) {
+		end := window.End
+		if end < window.Start {
+			end = 24 * time.Hour
+		}
+		if clock >= window.Start && clock < end {
+			return window, true
+		}
+	}
+	for _, window := range s.windowsOn(midnight.AddDate(0, 0, -1).Weekday()) {
+		if window.End < window.Start && clock < window.End {
+			return window, true
+		}
+	}
+	return config.RunWindow{}, false
+}
+
+// runSchedule applies the run windows until ctx is done: outside them the
+// queue is held and, in suspend mode, running encodes are suspended; inside
+// them the window concurrency is applied.
+func (c *Converter) runSchedule(ctx context.Context) {
+	sched := newSchedule(c.config.Schedule)
+	held := ""
+	if c.config.Schedule.Media == "video" {
+		held = "videos"
+	}
+	suspend := c.config.Schedule.Outside == "suspend"
+	if suspend && !canSuspend {
+		c.logger.Warn("Suspending encodes is not supported on this platform, running files finish outside the run windows")
+		suspend = false
+	}
+
+	var (
+		applied bool
+		current config.RunWindow
+		open    bool
+	)
+	apply := func(now time.Time) {
+		window, inside := sched.at(now)
+		if applied && inside == open && window == current {
+			return
+		}
+		applied, open, current = true, inside, window
+
+		c.jobs.setOutside(!inside, held, suspend)
+		if !inside {
+			if suspend {
+				c.logger.Info("🌙 Outside the run windows: new files wait, running files are suspended")
+			} else {
+				c.logger.Info("🌙 Outside the run windows: new files wait, running files finish")
+			}
+			return
+		}
+
+		message := "☀️  Run window open"
+		if window.Limit != 0 {
+			limit := window.Limit
+			if limit == config.RunWindowMax {
+				limit = math.MaxInt32 // capped to the workers of the phase
+			}
+			message += fmt.Sprintf(", concurrency %d", c.jobs.setLimit(limit))
+		}
+		c.logger.Info(message)
+	}
+
+	apply(time.Now())
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.jobs.setOutside(false, held, suspend)
+			return
+		case now := <-ticker.C:
+			apply(now)
+		}
+	}
+}
+
+// encodeContext bounds the encode of a queued file by a timeout, not counting
+// the time its processes spend suspended outside the run windows.
+func (c *Converter) encodeContext(parent context.Context, job string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		remaining := timeout
+		for {
+			suspended := c.jobs.suspension(job)
+			started := time.Now()
+			timer := time.NewTimer(remaining)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				cancel()
+				return
+			case <-suspended:
+				timer.Stop()
+				remaining -= time.Since(started)
+				select {
+				case <-ctx.Done():
+					return
+				case <-c.jobs.resumption():
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
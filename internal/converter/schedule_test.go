@@ -0,0 +1,202 @@
+package converter
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/config"
+)
+
+func TestScheduleAt(t *testing.T) {
+	sched := newSchedule(config.ScheduleConfig{
+		RunWindows: []string{"18:00-22:00@2", "22:00-07:00@max"},
+		Weekdays: map[string][]string{
+			"sat": {"00:00-24:00"},
+			"sun": {},
+		},
+	})
+
+	// 2024-01-05 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name  string
+		now   time.Time
+		open  bool
+		limit int
+	}{
+		{"friday afternoon", at(5, 14, 0), false, 0},
+		{"friday evening", at(5, 18, 0), true, 2},
+		{"friday night", at(5, 23, 30), true, config.RunWindowMax},
+		{"overnight from thursday", at(5, 6, 59), true, config.RunWindowMax},
+		{"saturday override", at(6, 14, 0), true, 0},
+		{"closed sunday", at(7, 14, 0), false, 0},
+		{"saturday window ends at midnight", at(7, 0, 30), false, 0},
+		{"monday morning after the window", at(8, 7, 0), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, open := sched.at(tt.now)
+			if open != tt.open || window.Limit != tt.limit {
+				t.Errorf("expected open=%v limit=%d, got open=%v limit=%d", tt.open, tt.limit, open, window.Limit)
+			}
+		})
+	}
+}
+
+func TestScheduleWithoutDefaultWindows(t *testing.T) {
+	// Only a weekday override: the other days are open all day
+	sched := newSchedule(config.ScheduleConfig{Weekdays: map[string][]string{"monday": {"20:00-23:00"}}})
+	if _, open := sched.at(time.Date(2024, 1, 9, 12, 0, 0, 0, time.Local)); !open {
+		t.Error("tuesday should be open")
+	}
+	if _, open := sched.at(time.Date(2024, 1, 8, 12, 0, 0, 0, time.Local)); open {
+		t.Error("monday noon should be closed")
+	}
+}
+
+func TestJobTrackerOutsideWindow(t *testing.T) {
+	tracker := newJobTracker()
+	tracker.startPhase("photo", []string{"a.jpg"}, NewAdaptiveLimiter(1), false, 1, 1)
+
+	// Holding only videos leaves the photo phase running
+	tracker.setOutside(true, "videos", true)
+	if path, _ := tracker.next(); path != "a.jpg" {
+		t.Fatalf("photos should not be held, got %q", path)
+	}
+
+	tracker.begin("a.jpg", "photo")
+	if tracker.suspension("a.jpg") != nil {
+		t.Error("photos should not be suspended while only videos are held")
+	}
+	tracker.finish("a.jpg", "photo", time.Now(), nil)
+
+	tracker.startPhase("video", []string{"b.mov"}, NewAdaptiveLimiter(1), false, 1, 1)
+	select {
+	case <-tracker.suspension("b.mov"):
+	default:
+		t.Fatal("suspension should be signalled")
+	}
+	if path, more := tracker.next(); path != "" || !more {
+		t.Errorf("videos should be held outside the window, got %q", path)
+	}
+
+	resumed := tracker.resumption()
+	tracker.setOutside(false, "videos", true)
+	select {
+	case <-resumed:
+	default:
+		t.Fatal("resumption should be signalled")
+	}
+	if path, _ := tracker.next(); path != "b.mov" {
+		t.Errorf("expected b.mov once the window opens, got %q", path)
+	}
+}
+
+func TestEncodeContextExcludesSuspension(t *testing.T) {
+	c := &Converter{config: &config.Config{}, jobs: newJobTracker()}
+	ctx, cancel := c.encodeContext(context.Background(), "a.mov", 100*time.Millisecond)
+	defer cancel()
+
+	c.jobs.setOutside(true, "", true)
+	select {
+	case <-ctx.Done():
+		t.Fatal("suspended encode should not time out")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	c.jobs.setOutside(false, "", true)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("encode should time out once resumed")
+	}
+}
+
+func TestEncodeContextTimesOutWhenPhaseNotHeld(t *testing.T) {
+	c := &Converter{config: &config.Config{}, jobs: newJobTracker()}
+	c.jobs.startPhase("photo", []string{"a.jpg"}, NewAdaptiveLimiter(1), false, 1, 1)
+	c.jobs.next()
+	c.jobs.begin("a.jpg", "photo")
+
+	// Only videos are suspended: photos keep running and keep their timeout,
+	// including encodes started while suspended
+	for _, startSuspended := range []bool{false, true} {
+		c.jobs.setOutside(false, "videos", true)
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if startSuspended {
+			c.jobs.setOutside(true, "videos", true)
+			ctx, cancel = c.encodeContext(context.Background(), "a.jpg", 100*time.Millisecond)
+		} else {
+			ctx, cancel = c.encodeContext(context.Background(), "a.jpg", 100*time.Millisecond)
+			c.jobs.setOutside(true, "videos", true)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatalf("photo encode should time out while only videos are held (started suspended: %v)", startSuspended)
+		}
+		cancel()
+	}
+}
+
+func TestSuspendReachesPhotoProcesses(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process states are read from /proc")
+	}
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+	c := &Converter{config: &config.Config{}, jobs: newJobTracker()}
+	c.jobs.startPhase("photo", []string{"a.jpg"}, NewAdaptiveLimiter(1), false, 1, 1)
+	c.jobs.next()
+	c.jobs.begin("a.jpg", "photo")
+
+	// Holding only the video phase leaves photos running
+	c.jobs.setOutside(true, "videos", true)
+	cmd := exec.Command("sleep", "5")
+	detach, err := c.startProcess("a.jpg", cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	defer detach()
+	if state := processState(t, cmd.Process.Pid); state == "T" {
+		t.Error("a photo should keep running while only videos are held")
+	}
+	c.jobs.setOutside(false, "videos", true)
+
+	c.jobs.setOutside(true, "", true)
+	waitForState(t, cmd.Process.Pid, "T")
+	c.jobs.setOutside(false, "", true)
+	waitForState(t, cmd.Process.Pid, "S")
+}
+
+// processState reads the state letter from /proc/<pid>/stat.
+func processState(t *testing.T, pid int) string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))[0]
+}
+
+func waitForState(t *testing.T, pid int, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for processState(t, pid) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected process state %s, got %s", want, processState(t, pid))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
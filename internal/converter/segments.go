@@ -0,0 +1,279 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// segmentDirSuffix marks the working directory of a segmented encode, kept
+// next to the output until the segments are joined.
+const segmentDirSuffix = ".segments"
+
+// segmentSlotPoll is how often a segmented encode checks the phase limiter
+// for a free slot to encode one more segment in parallel.
+const segmentSlotPoll = time.Second
+
+// Files of the segment working directory
+const (
+	segmentSettingsFile = "settings.txt"
+	segmentSplitDone    = "split.done"
+	segmentConcatList   = "concat.txt"
+)
+
+// segmentJob holds what a segmented encode needs: the video arguments shared
+// by every segment and the arguments used when joining them.
+type segmentJob struct {
+	Queued     string // file reported with the progress
+	Encoder    string
+	Workdir    string
+	InputArgs  []string
+	VideoMap   []string
+	VideoArgs  []string
+	X265Params []string
+
+	// Join step: streams of the original (input 1) and output arguments
+	CodecTag   string
+	StreamArgs []string
+	OutputArgs []string
+}
+
+// shouldSegment reports whether a video is long enough to be encoded in
+// segments. Two-pass encodes need the whole file and are never segmented.
+func (c *Converter) shouldSegment(inputPath string, plan conversionPlan, profile videoEncodingProfile) bool {
+	cfg := c.config.Segments
+	if !cfg.Enabled || plan.Animation != nil || profile.TwoPass {
+		return false
+	}
+	duration, err := utils.GetVideoDuration(inputPath)
+	if err != nil || duration < cfg.MinDuration || duration < 2*cfg.SegmentDuration {
+		return false
+	}
+	c.logger.Info(fmt.Sprintf("🧩 %s: %s long, encoding in segments of ~%s",
+		filepath.Base(inputPath), c.formatDuration(duration), c.formatDuration(cfg.SegmentDuration)))
+	return true
+}
+
+// encodeSegmented splits the source video at keyframes, encodes every segment
+// not finished by a previous run and joins them with the original audio,
+// subtitles and chapters into tempPath. Each segment has its own timeout. The
+// working directory is kept on failure so the next run resumes.
+func (c *Converter) encodeSegmented(inputPath, filename, tempPath string, job segmentJob) error {
+	if err := c.prepareSegmentDir(job); err != nil {
+		return err
+	}
+
+	count, err := c.splitSegments(inputPath, job)
+	if err != nil {
+		return err
+	}
+
+	// The file's worker slot encodes one segment at a time; more run in
+	// parallel only on slots borrowed from the phase limiter
+	own := make(chan struct{}, 1)
+	own <- struct{}{}
+	borrowed := 0
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(context.Background())
+	for i := 0; i < count; i++ {
+		i := i
+		encoded := segmentPath(job.Workdir, "enc", i)
+		if _, err := os.Stat(encoded); err == nil {
+			continue // finished by a previous run
+		}
+		release, err := c.segmentSlot(groupCtx, own, &mu, &borrowed)
+		if err != nil {
+			break // a segment failed
+		}
+		group.Go(func() error {
+			defer release()
+			return c.encodeSegment(groupCtx, filename, i, count, job)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+
+	if err := c.joinSegments(inputPath, filename, tempPath, count, job); err != nil {
+		return err
+	}
+
+	// The joined file replaces the segments, whatever its verification says
+	os.RemoveAll(job.Workdir)
+	return nil
+}
+
+// segmentSlot waits for a slot to encode the next segment: the file's own,
+// or a free worker slot of the phase while fewer than segments.parallel - 1
+// are borrowed. A busy limiter is polled every segmentSlotPoll. The returned
+// function gives the slot back.
+func (c *Converter) segmentSlot(ctx context.Context, own chan struct{}, mu *sync.Mutex, borrowed *int) (func(), error) {
+	giveBack := func() { own <- struct{}{} }
+	ticker := time.NewTicker(segmentSlotPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-own:
+			return giveBack, nil
+		default:
+		}
+
+		mu.Lock()
+		if *borrowed < c.config.Segments.Parallel-1 {
+			if release, ok := c.jobs.borrowSlot(); ok {
+				*borrowed++
+				mu.Unlock()
+				return func() {
+					mu.Lock()
+					*borrowed--
+					mu.Unlock()
+					release()
+				}, nil
+			}
+		}
+		mu.Unlock()
+
+		select {
+		case <-own:
+			return giveBack, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// prepareSegmentDir creates the working directory, discarding segments left
+// by a run with different encoding settings.
+func (c *Converter) prepareSegmentDir(job segmentJob) error {
+	settings := strings.Join(append(append(append([]string{}, job.InputArgs...), job.VideoArgs...), job.X265Params...), " ") +
+		"\nsegment_seconds=" + strconv.Itoa(int(c.config.Segments.SegmentDuration.Seconds()))
+	settingsPath := filepath.Join(job.Workdir, segmentSettingsFile)
+
+	if previous, err := os.ReadFile(settingsPath); err == nil {
+		if string(previous) == settings {
+			return nil
+		}
+		c.logger.Warn(fmt.Sprintf("🧩 %s: encoding settings changed, discarding previous segments", filepath.Base(job.Workdir)))
+	}
+	if err := os.RemoveAll(job.Workdir); err != nil {
+		return fmt.Errorf("failed to reset segment directory: %w", err)
+	}
+	if err := os.MkdirAll(job.Workdir, 0755); err != nil {
+		return fmt.Errorf("failed to create segment directory: %w", err)
+	}
+	return os.WriteFile(settingsPath, []byte(settings), 0644)
+}
+
+// splitSegments cuts the primary video stream at keyframes without
+// re-encoding and returns the number of segments. The split runs once; its
+// result is recorded for later runs.
+func (c *Converter) splitSegments(inputPath string, job segmentJob) (int, error) {
+	donePath := filepath.Join(job.Workdir, segmentSplitDone)
+	if content, err := os.ReadFile(donePath); err == nil {
+		if count, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil && count > 0 {
+			return count, nil
+		}
+	}
+
+	ctx, cancel := c.encodeContext(context.Background(), job.Queued, c.config.ConversionTimeoutVideo)
+	defer cancel()
+
+	args := []string{"-i", inputPath}
+	args = append(args, job.VideoMap...)
+	args = append(args,
+		"-c", "copy",
+		"-f", "segment",
+		"-segment_time", strconv.Itoa(int(c.config.Segments.SegmentDuration.Seconds())),
+		"-segment_format", "matroska",
+		"-reset_timestamps", "1",
+		"-y", filepath.Join(job.Workdir, "src%04d.mkv"),
+	)
+	if output, err := c.processOutput(job.Queued, c.newFFmpegCommand(ctx, args...)); err != nil {
+		return 0, fmt.Errorf("failed to split video: %w - FFmpeg Error: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	sources, _ := filepath.Glob(filepath.Join(job.Workdir, "src*.mkv"))
+	if len(sources) == 0 {
+		return 0, fmt.Errorf("splitting produced no segments")
+	}
+	if err := os.WriteFile(donePath, []byte(strconv.Itoa(len(sources))), 0644); err != nil {
+		return 0, fmt.Errorf("failed to record segments: %w", err)
+	}
+	return len(sources), nil
+}
+
+// encodeSegment encodes one segment with its own timeout. The source segment
+// is removed once its encode is in place.
+func (c *Converter) encodeSegment(parent context.Context, filename string, index, count int, job segmentJob) error {
+	source := segmentPath(job.Workdir, "src", index)
+	encoded := segmentPath(job.Workdir, "enc", index)
+	tempPath := encoded + ".tmp"
+	defer os.Remove(tempPath)
+
+	ctx, cancel := c.encodeContext(parent, job.Queued, c.config.ConversionTimeoutVideo)
+	defer cancel()
+
+	args := append([]string{}, job.InputArgs...)
+	args = append(args, "-i", source)
+	args = append(args, job.VideoArgs...)
+	if len(job.X265Params) > 0 {
+		args = append(args, "-x265-params", strings.Join(job.X265Params, ":"))
+	}
+	args = append(args, "-an", "-sn", "-dn", "-f", "matroska")
+	args = append(args, progressArgs...)
+	args = append(args, "-y", tempPath)
+
+	label := fmt.Sprintf("%s [segment %d/%d]", filename, index+1, count)
+	cmd := c.newFFmpegCommand(ctx, args...)
+	if err := c.runVideoConversionWithProgress(cmd, source, label, encodePass{Number: 1, Total: 1, Start: time.Now(), Job: job.Queued, Encoder: job.Encoder}); err != nil {
+		return fmt.Errorf("segment %d/%d: %w", index+1, count, err)
+	}
+
+	if err := os.Rename(tempPath, encoded); err != nil {
+		return fmt.Errorf("failed to finalize segment %d/%d: %w", index+1, count, err)
+	}
+	os.Remove(source)
+	return nil
+}
+
+// joinSegments concatenates the encoded segments without re-encoding and
+// muxes them with the other streams and metadata of the original.
+func (c *Converter) joinSegments(inputPath, filename, tempPath string, count int, job segmentJob) error {
+	var list strings.Builder
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&list, "file '%s'\n", filepath.Base(segmentPath(job.Workdir, "enc", i)))
+	}
+	listPath := filepath.Join(job.Workdir, segmentConcatList)
+	if err := os.WriteFile(listPath, []byte(list.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write segment list: %w", err)
+	}
+
+	ctx, cancel := c.encodeContext(context.Background(), job.Queued, c.config.ConversionTimeoutVideo)
+	defer cancel()
+
+	args := []string{"-f", "concat", "-safe", "0", "-i", listPath, "-i", inputPath, "-map", "0:v:0", "-c:v", "copy"}
+	if job.CodecTag != "" {
+		args = append(args, "-tag:v", job.CodecTag)
+	}
+	args = append(args, job.StreamArgs...)
+	args = append(args, job.OutputArgs...)
+
+	cmd := c.newFFmpegCommand(ctx, args...)
+	if err := c.runVideoConversionWithProgress(cmd, inputPath, filename, encodePass{Number: 1, Total: 1, Start: time.Now(), Job: job.Queued, Encoder: "copy"}); err != nil {
+		return fmt.Errorf("failed to join segments: %w", err)
+	}
+	return nil
+}
+
+func segmentPath(workdir, kind string, index int) string {
+	return filepath.Join(workdir, fmt.Sprintf("%s%04d.mkv", kind, index))
+}
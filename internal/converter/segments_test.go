@@ -0,0 +1,95 @@
+package converter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/config"
+	"github.com/kevindurb/media-converter/internal/logger"
+)
+
+func TestPrepareSegmentDirResumesOnlyWithSameSettings(t *testing.T) {
+	dir := t.TempDir()
+	log, err := logger.NewLogger(filepath.Join(dir, "conversion.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{
+		config: &config.Config{Segments: config.SegmentConfig{SegmentDuration: 300 * time.Second}},
+		logger: log,
+	}
+
+	job := segmentJob{Workdir: filepath.Join(dir, "clip.mp4"+segmentDirSuffix), VideoArgs: []string{"-c:v", "libx265", "-crf", "28"}}
+	if err := c.prepareSegmentDir(job); err != nil {
+		t.Fatal(err)
+	}
+	finished := segmentPath(job.Workdir, "enc", 0)
+	if err := os.WriteFile(finished, []byte("segment"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same settings: finished segments are kept for the resumed run
+	if err := c.prepareSegmentDir(job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(finished); err != nil {
+		t.Fatalf("finished segment was discarded: %v", err)
+	}
+
+	// Different CRF: segments would not match, start over
+	job.VideoArgs = []string{"-c:v", "libx265", "-crf", "24"}
+	if err := c.prepareSegmentDir(job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(finished); !os.IsNotExist(err) {
+		t.Fatalf("segment encoded with other settings was kept")
+	}
+}
+
+func TestSegmentSlotsBorrowFromPhaseLimiter(t *testing.T) {
+	c := &Converter{config: &config.Config{Segments: config.SegmentConfig{Parallel: 3}}, jobs: newJobTracker()}
+	limiter := NewAdaptiveLimiter(2)
+	c.jobs.startPhase("video", []string{"a.mov", "b.mov"}, limiter, false, 2, 2)
+	limiter.Acquire() // the worker encoding a.mov
+
+	own := make(chan struct{}, 1)
+	own <- struct{}{}
+	var mu sync.Mutex
+	borrowed := 0
+	ctx := context.Background()
+
+	first, err := c.segmentSlot(ctx, own, &mu, &borrowed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.segmentSlot(ctx, own, &mu, &borrowed)
+	if err != nil || limiter.Active() != 2 {
+		t.Fatalf("expected the second segment on a borrowed slot, %d active (%v)", limiter.Active(), err)
+	}
+
+	// The limiter is full: a third segment waits despite segments.parallel 3
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.segmentSlot(timeout, own, &mu, &borrowed); err == nil {
+		t.Fatal("a third segment should wait for a free slot")
+	}
+
+	second()
+	first()
+	if limiter.Active() != 1 {
+		t.Errorf("expected the borrowed slot returned, %d active", limiter.Active())
+	}
+
+	// Outside the run windows no slot is borrowed
+	c.jobs.setOutside(true, "", false)
+	<-own
+	timeout, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.segmentSlot(timeout, own, &mu, &borrowed); err == nil || limiter.Active() != 1 {
+		t.Errorf("no slot should be borrowed while the queue is held, %d active", limiter.Active())
+	}
+}
@@ -0,0 +1,147 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+// Text subtitle codecs that can be converted between containers. Bitmap
+// subtitles (PGS, VobSub, DVB) can only be copied into Matroska.
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"webvtt":   true,
+	"mov_text": true,
+	"text":     true,
+}
+
+// Upper bound for re-encoded audio tracks.
+const maxAudioBitrate = 512_000
+
+// streamMapping is the explicit ffmpeg stream selection for a source.
+type streamMapping struct {
+	// VideoArgs selects the primary video stream, Args everything else
+	VideoArgs []string
+	Args      []string
+	Expected  utils.StreamLayout
+	Dropped   []string
+
+	// AudioBitrate is the combined bitrate of the output audio tracks
+	AudioBitrate int64
+}
+
+// buildStreamMapping maps the primary video stream, every audio track,
+// subtitles, chapters and timecode of a source into the given container.
+// The source is ffmpeg input number input. Streams the container cannot hold
+// are listed in Dropped.
+func buildStreamMapping(probe *utils.MediaProbe, container videoContainer, input int) streamMapping {
+	mapping := streamMapping{Expected: utils.StreamLayout{Video: 1}}
+
+	video := probe.VideoStream()
+	if video == nil {
+		mapping.VideoArgs = []string{"-map", fmt.Sprintf("%d:v:0", input)}
+		return mapping
+	}
+	mapping.VideoArgs = []string{"-map", fmt.Sprintf("%d:%d", input, video.Index)}
+
+	for _, stream := range probe.StreamsOfType("audio") {
+		out := mapping.Expected.Audio
+		mapping.Args = append(mapping.Args, "-map", fmt.Sprintf("%d:%d", input, stream.Index))
+		mapping.Args = append(mapping.Args, audioTrackArgs(stream, out, container)...)
+		mapping.AudioBitrate += audioTrackBitrate(stream, container)
+		mapping.Expected.Audio++
+	}
+
+	for _, stream := range probe.StreamsOfType("subtitle") {
+		codec := container.SubtitleCodec
+		if !textSubtitleCodecs[stream.CodecName] {
+			if !container.KeepBitmapSubtitles {
+				mapping.Dropped = append(mapping.Dropped, fmt.Sprintf("subtitle #%d (%s)", stream.Index, stream.CodecName))
+				continue
+			}
+			codec = "copy"
+		}
+		mapping.Args = append(mapping.Args,
+			"-map", fmt.Sprintf("%d:%d", input, stream.Index),
+			fmt.Sprintf("-c:s:%d", mapping.Expected.Subtitles), codec)
+		mapping.Expected.Subtitles++
+	}
+
+	// Timecode tracks are recreated from their start value; other data tracks
+	// (telemetry, proprietary metadata) cannot be muxed
+	timecode := ""
+	for _, stream := range probe.StreamsOfType("data") {
+		if tc := stream.Tag("timecode"); tc != "" {
+			timecode = tc
+			continue
+		}
+		mapping.Dropped = append(mapping.Dropped, fmt.Sprintf("data #%d (%s)", stream.Index, dataStreamName(stream)))
+	}
+	if timecode == "" {
+		timecode = video.Tag("timecode")
+	}
+	if timecode != "" && container.SupportsTimecode {
+		mapping.Args = append(mapping.Args, "-timecode", timecode, "-write_tmcd", "1")
+	}
+
+	mapping.Args = append(mapping.Args, "-map_chapters", strconv.Itoa(input))
+	mapping.Expected.Chapters = len(probe.Chapters)
+
+	return mapping
+}
+
+// audioTrackArgs keeps tracks the container stores natively (AAC/Opus in MP4,
+// Opus in Matroska) and re-encodes the others with the source channel count
+// and a bitrate matching it.
+func audioTrackArgs(stream utils.ProbeStream, out int, container videoContainer) []string {
+	if container.CopyAudio[stream.CodecName] {
+		return []string{fmt.Sprintf("-c:a:%d", out), "copy"}
+	}
+
+	channels := stream.Channels
+	if channels <= 0 {
+		channels = 2
+	}
+	return []string{
+		fmt.Sprintf("-c:a:%d", out), container.AudioCodec,
+		fmt.Sprintf("-b:a:%d", out), utils.FormatBitrate(audioTrackBitrate(stream, container)),
+		fmt.Sprintf("-ac:a:%d", out), strconv.Itoa(channels),
+	}
+}
+
+// audioTrackBitrate returns the output bitrate of an audio track: the source
+// bitrate for copied tracks, otherwise a per-channel budget capped at the
+// source bitrate.
+func audioTrackBitrate(stream utils.ProbeStream, container videoContainer) int64 {
+	source := stream.BitRateValue()
+	if container.CopyAudio[stream.CodecName] {
+		if source > 0 {
+			return source
+		}
+		return 128_000
+	}
+
+	channels := stream.Channels
+	if channels <= 0 {
+		channels = 2
+	}
+	bitrate := int64(channels * container.AudioBitratePerChannel)
+	if source > 0 && source < bitrate {
+		bitrate = source
+	}
+	return int64(clampInt(int(bitrate), container.AudioBitratePerChannel, maxAudioBitrate))
+}
+
+func dataStreamName(stream utils.ProbeStream) string {
+	if handler := stream.Tag("handler_name"); handler != "" {
+		return handler
+	}
+	if stream.CodecTagString != "" {
+		return stream.CodecTagString
+	}
+	return stream.CodecName
+}
@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/utils"
+)
+
+func TestBuildStreamMappingKeepsEveryTrack(t *testing.T) {
+	probe := &utils.MediaProbe{
+		Streams: []utils.ProbeStream{
+			{Index: 0, CodecType: "video", CodecName: "h264"},
+			{Index: 1, CodecType: "audio", CodecName: "aac", Channels: 2},
+			{Index: 2, CodecType: "audio", CodecName: "pcm_s16le", Channels: 6},
+			{Index: 3, CodecType: "subtitle", CodecName: "subrip"},
+			{Index: 4, CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle"},
+			{Index: 5, CodecType: "data", CodecTagString: "tmcd", Tags: map[string]string{"timecode": "01:00:00:00"}},
+		},
+		Chapters: []utils.ProbeChapter{{ID: 0}, {ID: 1}},
+	}
+
+	mapping := buildStreamMapping(probe, videoContainers["mp4"], 0)
+	args := strings.Join(append(mapping.VideoArgs, mapping.Args...), " ")
+
+	for _, want := range []string{
+		"-map 0:0", "-map 0:1 -c:a:0 copy",
+		"-map 0:2 -c:a:1 aac -b:a:1 384k -ac:a:1 6",
+		"-map 0:3 -c:s:0 mov_text",
+		"-timecode 01:00:00:00", "-map_chapters 0",
+	} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("expected %q in mapping, got %q", want, args)
+		}
+	}
+	if strings.Contains(args, "0:4") {
+		t.Fatalf("bitmap subtitles must not be mapped: %q", args)
+	}
+
+	expected := utils.StreamLayout{Video: 1, Audio: 2, Subtitles: 1, Chapters: 2}
+	if mapping.Expected != expected {
+		t.Fatalf("expected layout %s, got %s", expected, mapping.Expected)
+	}
+	if len(mapping.Dropped) != 1 {
+		t.Fatalf("expected the PGS track to be reported as dropped, got %v", mapping.Dropped)
+	}
+}
+
+func TestBuildStreamMappingFollowsContainer(t *testing.T) {
+	probe := &utils.MediaProbe{
+		Streams: []utils.ProbeStream{
+			{Index: 0, CodecType: "video", CodecName: "hevc"},
+			{Index: 1, CodecType: "audio", CodecName: "aac", Channels: 2},
+			{Index: 2, CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle"},
+		},
+	}
+
+	// Matroska stores Opus audio and keeps bitmap subtitles
+	args := strings.Join(buildStreamMapping(probe, videoContainers["mkv"], 0).Args, " ")
+	for _, want := range []string{"-c:a:0 libopus -b:a:0 96k", "-map 0:2 -c:s:0 copy"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("expected %q in mkv mapping, got %q", want, args)
+		}
+	}
+
+	// WebM has no bitmap subtitle support
+	mapping := buildStreamMapping(probe, videoContainers["webm"], 0)
+	if mapping.Expected.Subtitles != 0 || len(mapping.Dropped) != 1 {
+		t.Fatalf("expected the PGS track to be dropped for webm, got %+v", mapping)
+	}
+}
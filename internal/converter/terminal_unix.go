@@ -0,0 +1,19 @@
+//go:build !windows
+
+package converter
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalSize returns the columns and rows of the terminal on stdout, or
+// zeros when unknown.
+func terminalSize() (int, int) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0
+	}
+	return int(ws.Col), int(ws.Row)
+}
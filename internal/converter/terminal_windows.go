@@ -0,0 +1,19 @@
+//go:build windows
+
+package converter
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// terminalSize returns the columns and rows of the console window on stdout,
+// or zeros when unknown.
+func terminalSize() (int, int) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(os.Stdout.Fd()), &info); err != nil {
+		return 0, 0
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1
+}
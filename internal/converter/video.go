@@ -1,7 +1,6 @@
 package converter
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -9,24 +8,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/kevindurb/media-converter/internal/utils"
 )
 
-// VideoAccelerationInfo stocke les informations sur l'accélération disponible
-type VideoAccelerationInfo struct {
-	Available   bool
-	Message     string
-	Codec       string
-	Preset      string
-	HwAccelArgs []string
-	OutputTag   string
-}
-
 type videoEncodingProfile struct {
 	Codec         string
 	Args          []string
@@ -34,72 +21,131 @@ type videoEncodingProfile struct {
 	OutputTag     string
 	UsingHardware bool
 	LogMessage    string
+	TwoPass       bool
+	X265Params    []string
+
+	// Hardware encoder, set when UsingHardware
+	Hardware hardwareEncoder
+}
+
+// crfRanges bounds the CRF used for each target codec.
+var crfRanges = map[string][2]int{
+	"h264": {18, 30},
+	"h265": {18, 32},
+	"av1":  {av1MinCRF, av1MaxCRF},
 }
 
-func (c *Converter) buildVideoEncodingProfile(inputPath string) (videoEncodingProfile, error) {
-	targetCodec := normalizeVideoCodec(c.config.VideoCodec)
-	accelerationInfo := c.getVideoAccelerationInfo()
+func (c *Converter) buildVideoEncodingProfile(inputPath string, plan conversionPlan, audioBitrate int64) (videoEncodingProfile, error) {
+	targetCodec := normalizeVideoCodec(plan.Codec)
+	crfRange := crfRanges[targetCodec]
+
+	// Hardware encoders are single-pass, so 2pass always uses software
+	if c.config.VideoAcceleration && c.config.VideoMode != "2pass" {
+		if hw, ok := c.hardwareEncoderFor(targetCodec); ok {
+			crf := c.clampCRF(hw.Encoder, plan.Quality, crfRange[0], crfRange[1])
+			return c.hardwareProfile(hw, crf, inputPath), nil
+		}
+	}
 
 	switch targetCodec {
 	case "h264":
-		crf := clampInt(c.config.VideoCRF, 18, 30)
+		crf := c.clampCRF("libx264", plan.Quality, crfRange[0], crfRange[1])
+		rateArgs, rateDesc, twoPass, err := c.softwareRateControl("libx264", crf, inputPath, audioBitrate)
+		if err != nil {
+			return videoEncodingProfile{}, err
+		}
 		return videoEncodingProfile{
 			Codec:      "libx264",
-			Args:       []string{"-crf", strconv.Itoa(crf), "-preset", "medium"},
-			LogMessage: fmt.Sprintf("📹 Using software encoding: libx264 (CRF %d, preset medium)", crf),
+			Args:       append(rateArgs, "-preset", "medium"),
+			TwoPass:    twoPass,
+			LogMessage: fmt.Sprintf("📹 Using software encoding: libx264 (%s, preset medium)", rateDesc),
 		}, nil
 	case "av1":
-		crf := clampInt(c.config.VideoCRF, 28, 45)
+		encoder, note, err := chooseAV1Encoder(c.config.AV1.Encoder, c.availableEncoders(), c.config.VideoMode == "2pass")
+		if err != nil {
+			return videoEncodingProfile{}, err
+		}
+		if note != "" {
+			c.warnOnce("av1:"+note, "📹 "+note)
+		}
+		if c.config.AV1.FilmGrain > 0 && encoder == "librav1e" {
+			c.warnOnce("av1:grain", "📹 librav1e has no film-grain synthesis, --av1-film-grain is ignored")
+		}
+
+		crf := c.clampCRF(encoder, plan.Quality, crfRange[0], crfRange[1])
+		rateArgs, rateDesc, twoPass, err := c.softwareRateControl(encoder, crf, inputPath, audioBitrate)
+		if err != nil {
+			return videoEncodingProfile{}, err
+		}
+		message := fmt.Sprintf("📹 Using software encoding: %s (%s, speed %s)", encoder, rateDesc, c.config.AV1.Speed)
+		if c.config.AV1.FilmGrain > 0 && encoder != "librav1e" {
+			message += fmt.Sprintf(", film grain %d", c.config.AV1.FilmGrain)
+		}
 		return videoEncodingProfile{
-			Codec:      "libaom-av1",
-			Args:       []string{"-crf", strconv.Itoa(crf), "-b:v", "0", "-cpu-used", "4"},
-			LogMessage: fmt.Sprintf("📹 Using software encoding: libaom-av1 (CRF %d)", crf),
+			Codec:      encoder,
+			Args:       append(rateArgs, av1EncoderArgs(encoder, c.config.AV1.Speed, c.config.AV1.FilmGrain)...),
+			TwoPass:    twoPass,
+			LogMessage: message,
 		}, nil
 	default:
-		if accelerationInfo.Available && c.config.VideoAcceleration {
-			duration, err := utils.GetVideoDuration(inputPath)
-			if err != nil {
-				c.logger.Warn(fmt.Sprintf("Unable to read video duration for bitrate estimation: %v", err))
-			}
-
-			bitrate, bufsize := c.estimateHardwareBitrate(inputPath, duration)
-
-			args := []string{"-b:v", bitrate, "-maxrate", bitrate}
-			if bufsize != "" {
-				args = append(args, "-bufsize", bufsize)
-			}
-
-			message := accelerationInfo.Message
-			if message == "" {
-				message = "VideoToolbox HEVC"
-			}
-
-			return videoEncodingProfile{
-				Codec:         accelerationInfo.Codec,
-				Args:          args,
-				HwAccelArgs:   accelerationInfo.HwAccelArgs,
-				OutputTag:     accelerationInfo.OutputTag,
-				UsingHardware: true,
-				LogMessage:    fmt.Sprintf("📹 Using hardware acceleration: %s (target bitrate %s)", message, bitrate),
-			}, nil
+		crf := c.clampCRF("libx265", plan.Quality, crfRange[0], crfRange[1])
+		rateArgs, rateDesc, twoPass, err := c.softwareRateControl("libx265", crf, inputPath, audioBitrate)
+		if err != nil {
+			return videoEncodingProfile{}, err
 		}
+		return videoEncodingProfile{
+			Codec:      "libx265",
+			Args:       append(rateArgs, "-preset", "medium"),
+			TwoPass:    twoPass,
+			LogMessage: fmt.Sprintf("📹 Using software encoding: libx265 (%s, preset medium)", rateDesc),
+		}, nil
+	}
+}
 
-		crf := clampInt(c.config.VideoCRF, 18, 32)
-		preset := accelerationInfo.Preset
-		if preset == "" {
-			preset = "medium"
+// hardwareProfile maps the CRF to the backend's constant-quality mode, or to
+// a bitrate estimated from the source for bitrate-driven backends. In cq mode
+// the configured ceiling caps the bitrate.
+func (c *Converter) hardwareProfile(hw hardwareEncoder, crf int, inputPath string) videoEncodingProfile {
+	maxrate, _ := utils.ParseBitrate(c.config.VideoMaxBitrate)
+	capped := c.config.VideoMode == "cq" && maxrate > 0
+
+	var args []string
+	var rateDesc string
+	if hw.Backend.Quality != nil {
+		args = hw.Backend.Quality(hw.Codec, crf)
+		rateDesc = fmt.Sprintf("quality %d", crf)
+		if capped {
+			args = append(args, "-maxrate", utils.FormatBitrate(maxrate), "-bufsize", utils.FormatBitrate(2*maxrate))
+			rateDesc += ", max " + utils.FormatBitrate(maxrate)
+		}
+	} else {
+		duration, err := utils.GetVideoDuration(inputPath)
+		if err != nil {
+			c.logger.Warn(fmt.Sprintf("Unable to read video duration for bitrate estimation: %v", err))
 		}
 
-		message := accelerationInfo.Message
-		if message == "" {
-			message = "libx265"
+		bitrate, bufsize := c.estimateHardwareBitrate(inputPath, duration)
+		args = []string{"-b:v", bitrate, "-maxrate", bitrate}
+		if bufsize != "" {
+			args = append(args, "-bufsize", bufsize)
+		}
+		if capped {
+			if estimate, err := utils.ParseBitrate(bitrate); err == nil && estimate > maxrate {
+				bitrate = utils.FormatBitrate(maxrate)
+			}
+			args = []string{"-b:v", bitrate, "-maxrate", utils.FormatBitrate(maxrate), "-bufsize", utils.FormatBitrate(2 * maxrate)}
 		}
+		rateDesc = "target bitrate " + bitrate
+	}
 
-		return videoEncodingProfile{
-			Codec:      "libx265",
-			Args:       []string{"-crf", strconv.Itoa(crf), "-preset", preset},
-			LogMessage: fmt.Sprintf("📹 Using software encoding: %s (CRF %d, preset %s)", message, crf, preset),
-		}, nil
+	return videoEncodingProfile{
+		Codec:         hw.Encoder,
+		Args:          args,
+		HwAccelArgs:   hw.Backend.HwAccel(hw.Device),
+		OutputTag:     hw.Backend.OutputTags[hw.Codec],
+		UsingHardware: true,
+		Hardware:      hw,
+		LogMessage:    fmt.Sprintf("📹 Using hardware acceleration: %s (%s)", hw.describe(), rateDesc),
 	}
 }
 
@@ -151,11 +197,20 @@ func (c *Converter) estimateHardwareBitrate(inputPath string, duration time.Dura
 	return fmt.Sprintf("%.2fM", targetMbps), fmt.Sprintf("%.2fM", bufferMbps)
 }
 
-func (c *Converter) convertVideo(inputPath string) error {
+func (c *Converter) convertVideo(inputPath string, plan conversionPlan) error {
+	queued := inputPath // reported with the encode progress and metrics
 	filename := filepath.Base(inputPath)
 	name := strings.TrimSuffix(filename, filepath.Ext(filename))
 
-	fileDate, err := utils.GetFileDate(inputPath)
+	// Chapters are named after their recording and dated by its first file
+	datePath := inputPath
+	if plan.Chapters != nil {
+		filename = plan.Chapters.Label()
+		name = plan.Chapters.OutputName()
+		datePath = plan.Chapters.Group.Chapters[0]
+	}
+
+	fileDate, err := utils.GetFileDate(datePath)
 	if err != nil {
 		c.logger.Warn(fmt.Sprintf("Could not extract date from %s: %v - skipping file", filename, err))
 		return fmt.Errorf("unable to determine file date: %w", err)
@@ -167,8 +222,9 @@ func (c *Converter) convertVideo(inputPath string) error {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	// Generate base filename and check if already converted (always use mp4 for output)
-	baseName := utils.CleanFilename(name, "mp4", fileDate, 1)
+	// Generate base filename and check if already converted in the chosen container
+	container := c.outputContainer(inputPath, plan)
+	baseName := utils.CleanFilename(name, container.Extension, fileDate, 1)
 	baseOutputPath := filepath.Join(destPath, baseName)
 
 	// Check if file already exists and is valid (idempotency check with integrity verification)
@@ -179,6 +235,7 @@ func (c *Converter) convertVideo(inputPath string) error {
 			c.stats.mu.Lock()
 			c.stats.skippedFiles++
 			c.stats.mu.Unlock()
+			c.metrics.fileEvent(eventSkipped, "video")
 			return nil
 		} else {
 			// File is corrupted, remove it and proceed with conversion
@@ -187,9 +244,25 @@ func (c *Converter) convertVideo(inputPath string) error {
 			c.stats.mu.Lock()
 			c.stats.recoveredFiles++
 			c.stats.mu.Unlock()
+			c.metrics.fileEvent(eventRecovered, "video")
 		}
 	}
 
+	// A previous run may have kept the original because its encode was not
+	// smaller. Kept chapters are copied under their own names.
+	keptBase := name
+	if plan.Chapters != nil {
+		keptBase = strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	}
+	if keptName, ok := c.findKeptOriginal(inputPath, destPath, keptBase, fileDate); ok {
+		c.logger.Info(fmt.Sprintf("📹 %s -> %s (original already kept, skipping)", filename, keptName))
+		c.stats.mu.Lock()
+		c.stats.skippedFiles++
+		c.stats.mu.Unlock()
+		c.metrics.fileEvent(eventSkipped, "video")
+		return nil
+	}
+
 	// Use the base name for conversion
 	cleanName := baseName
 	outputPath := baseOutputPath
@@ -201,6 +274,14 @@ func (c *Converter) convertVideo(inputPath string) error {
 		return nil
 	}
 
+	// The chapters of a joined group are the originals; the encode reads their
+	// lossless concatenation
+	originals := []string{inputPath}
+	joined := plan.Chapters != nil && plan.Chapters.Part == 0
+	if joined {
+		originals = plan.Chapters.Group.Chapters
+	}
+
 	// Create processing marker
 	if err := c.security.CreateProcessingMarker(outputPath); err != nil {
 		c.logger.Warn(fmt.Sprintf("Failed to create processing marker: %v", err))
@@ -214,57 +295,255 @@ func (c *Converter) convertVideo(inputPath string) error {
 		}
 	}()
 
-	profile, err := c.buildVideoEncodingProfile(inputPath)
+	if joined {
+		joinedPath := outputPath + ".joined.tmp"
+		defer os.Remove(joinedPath)
+		c.logger.Info(fmt.Sprintf("🧩 %s: joining chapters", filename))
+		if err := c.joinChapters(queued, plan.Chapters.Group, outputPath+".chapters.tmp", joinedPath); err != nil {
+			return err
+		}
+		inputPath = joinedPath
+	}
+
+	// Read the stream inventory once: it drives stream mapping, metadata and
+	// the audio share of a two-pass size budget
+	var probe *utils.MediaProbe
+	var mapping *streamMapping
+	var sourceMeta *videoMetadata
+	var probeErr error
+	if plan.Animation == nil {
+		if probe, probeErr = utils.ProbeMedia(inputPath); probeErr == nil {
+			m := buildStreamMapping(probe, container, 0)
+			mapping = &m
+			meta := readVideoMetadata(probe)
+			sourceMeta = &meta
+		}
+	}
+
+	var audioBitrate int64
+	if mapping != nil {
+		audioBitrate = mapping.AudioBitrate
+	}
+
+	profile, err := c.buildVideoEncodingProfile(inputPath, plan, audioBitrate)
 	if err != nil {
 		return err
 	}
 
 	if profile.LogMessage != "" {
-		c.logger.Info(profile.LogMessage)
+		if plan.RuleName != "" {
+			c.logger.Info(fmt.Sprintf("%s [%s]", profile.LogMessage, plan.RuleName))
+		} else {
+			c.logger.Info(profile.LogMessage)
+		}
 	}
 
-	// Convert to temporary file with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), c.config.ConversionTimeoutVideo)
-	defer cancel()
+	// Rotated phone videos are turned upright by a filter or keep their
+	// display matrix, depending on the rotation mode
+	var rotation rotationSettings
+	if probe != nil {
+		if video := probe.VideoStream(); video != nil {
+			rotation = planRotation(c.config.RotationMode, video.Rotation())
+		}
+	}
 
-	// Build ffmpeg command based on acceleration
-	var ffmpegArgs []string
-	if len(profile.HwAccelArgs) > 0 {
-		ffmpegArgs = append(ffmpegArgs, profile.HwAccelArgs...)
+	// Decoding arguments, including hardware decoding. Autorotation is
+	// disabled: hardware frames and segments would bypass it
+	decodeArgs := append([]string{}, profile.HwAccelArgs...)
+	if probe != nil {
+		decodeArgs = append(decodeArgs, "-noautorotate")
+		decodeArgs = append(decodeArgs, rotation.InputArgs...)
 	}
+	inputArgs := append(append([]string{}, decodeArgs...), "-i", inputPath)
+
+	// Video encoding arguments, shared by both passes of a two-pass encode
+	videoArgs := []string{"-c:v", profile.Codec}
+	videoArgs = append(videoArgs, profile.Args...)
 
-	ffmpegArgs = append(ffmpegArgs,
-		"-i", inputPath,
-		"-c:v", profile.Codec,
-	)
+	if profile.OutputTag != "" && container.SupportsCodecTag {
+		videoArgs = append(videoArgs, "-tag:v", profile.OutputTag)
+	}
 
-	ffmpegArgs = append(ffmpegArgs, profile.Args...)
+	// Video filters are collected and applied as a single chain
+	var filters []string
+	x265Params := append([]string{}, profile.X265Params...)
 
-	if profile.OutputTag != "" {
-		ffmpegArgs = append(ffmpegArgs, "-tag:v", profile.OutputTag)
+	// Interlaced camcorder footage is deinterlaced before any other filter
+	var deinterlace deinterlaceSettings
+	isInterlaced := false
+	if probe != nil {
+		if deinterlace, isInterlaced = c.planDeinterlace(queued, inputPath, probe.VideoStream()); isInterlaced {
+			c.logger.Info(fmt.Sprintf("🎞️  %s: interlaced (%s), deinterlacing with %s", filename, deinterlace.Reason, deinterlace.Filter))
+			filters = append(filters, deinterlace.Filter)
+		}
 	}
 
-	ffmpegArgs = append(ffmpegArgs,
-		"-c:a", "aac", "-b:a", "128k",
-		"-movflags", "+faststart",
-		"-map_metadata", "0",
-		"-f", "mp4",
-		"-progress", "pipe:2",
-		"-y", tempPath,
-	)
+	switch {
+	case rotation.Filter != "":
+		c.logger.Info(fmt.Sprintf("🔄 %s: rotated %d°, turning the pixels upright", filename, rotation.Rotation))
+		filters = append(filters, rotation.Filter)
+	case rotation.KeepsMatrix():
+		c.logger.Info(fmt.Sprintf("🔄 %s: rotated %d°, keeping the display matrix", filename, rotation.Rotation))
+	}
 
-	cmd := c.newFFmpegCommand(ctx, ffmpegArgs...)
+	// VFR screen recordings and high-speed footage keep their timestamps, or
+	// every video is conformed to a constant rate
+	slowMotionLost := false
+	if probe != nil {
+		if timing, ok := readFrameTiming(probe); ok {
+			if timing.Variable || timing.HighSpeed {
+				c.logger.Info(fmt.Sprintf("⏱️  %s: %s", filename, timing.describe()))
+			}
+			if fps, apply := planFrameRate(c.config.FrameRate.Mode, c.config.FrameRate.Conform, timing); apply {
+				if fps.Filter != "" {
+					filters = append(filters, fps.Filter)
+					sourceMeta.FrameRate = 0
+				}
+				videoArgs = append(videoArgs, fps.Args...)
+				if fps.SlowMotionLost {
+					c.logger.Warn(fmt.Sprintf("🐢 %s: conforming to %s fps drops the high-speed frames", filename, formatFrameRate(c.config.FrameRate.Conform)))
+					slowMotionLost = true
+				}
+			}
+			if timing.RampTrack && !slowMotionLost {
+				// Timed metadata tracks cannot be muxed; the frames are kept
+				c.logger.Warn(fmt.Sprintf("🐢 %s: slow-motion ramp track cannot be kept, the %s fps frames play at normal speed", filename, formatFrameRate(timing.Rate)))
+				slowMotionLost = true
+			}
+		}
+	}
+
+	// HDR sources keep 10-bit PQ/HLG signalling or are tone-mapped to SDR
+	var hdr hdrSettings
+	isHDR := false
+	if plan.Animation == nil {
+		if hdr, isHDR = c.planHDR(inputPath, profile); isHDR {
+			c.logger.Info(fmt.Sprintf("🌈 %s: HDR source (%s)", filename, hdr.describe()))
+			filters = append(filters, hdr.Filters...)
+			videoArgs = append(videoArgs, hdr.Args...)
+			x265Params = append(x265Params, hdr.X265Params...)
+			videoArgs = appendSVTAV1Params(videoArgs, hdr.SVTAV1Params)
+		}
+	}
 
-	// Start the command and monitor progress
-	if err := c.runVideoConversionWithProgress(cmd, inputPath, filename); err != nil {
+	if plan.Animation != nil {
+		// Animated images: even dimensions for 4:2:0
+		filters = append(filters, "scale=trunc(iw/2)*2:trunc(ih/2)*2")
+		if !profile.Hardware.Backend.Upload {
+			videoArgs = append(videoArgs, "-pix_fmt", "yuv420p")
+		}
+	}
+
+	// Backends encoding from hardware surfaces upload the filtered frames last
+	if profile.UsingHardware {
+		if upload := profile.Hardware.uploadFilter(isHDR && !hdr.Tonemapped); upload != "" {
+			filters = append(filters, upload)
+		}
+	}
+
+	if len(filters) > 0 {
+		videoArgs = append(videoArgs, "-vf", strings.Join(filters, ","))
+	}
+
+	// Map every stream explicitly from the source inventory and carry over
+	// the key container tags (GPS, make/model)
+	var streamArgs, videoMap []string
+	switch {
+	case plan.Animation != nil:
+		streamArgs = append(streamArgs, "-an")
+	case mapping != nil:
+		videoMap = mapping.VideoArgs
+		streamArgs = append(streamArgs, mapping.VideoArgs...)
+		streamArgs = append(streamArgs, mapping.Args...)
+		if len(mapping.Dropped) > 0 {
+			c.logger.Warn(fmt.Sprintf("📹 %s: streams not supported in %s are dropped: %s", filename, strings.ToUpper(container.Name), strings.Join(mapping.Dropped, ", ")))
+		}
+		streamArgs = append(streamArgs, sourceMeta.metadataArgs()...)
+	default:
+		c.logger.Warn(fmt.Sprintf("📹 %s: stream inventory unavailable (%v), using default stream selection", filename, probeErr))
+		streamArgs = append(streamArgs, "-c:a", container.AudioCodec,
+			"-b:a", fmt.Sprintf("%dk", 2*container.AudioBitratePerChannel/1000))
+	}
+
+	outputArgs := append([]string{}, container.MuxerArgs...)
+	outputArgs = append(outputArgs, "-map_metadata", "0", "-f", container.Muxer)
+	outputArgs = append(outputArgs, progressArgs...)
+	outputArgs = append(outputArgs, "-y", tempPath)
+
+	// Long videos are encoded in resumable segments, each with its own timeout.
+	// Segments are Matroska files, which may not carry a display matrix, so
+	// videos keeping theirs are encoded whole
+	if mapping != nil && !rotation.KeepsMatrix() && c.shouldSegment(inputPath, plan, profile) {
+		job := segmentJob{
+			Queued:     queued,
+			Encoder:    profile.Codec,
+			Workdir:    outputPath + segmentDirSuffix,
+			InputArgs:  decodeArgs,
+			VideoMap:   mapping.VideoArgs,
+			VideoArgs:  videoArgs,
+			X265Params: x265Params,
+		}
+		if profile.OutputTag != "" && container.SupportsCodecTag {
+			job.CodecTag = profile.OutputTag
+		}
+		joined := buildStreamMapping(probe, container, 1)
+		job.StreamArgs = append(append(job.StreamArgs, joined.Args...), sourceMeta.metadataArgs()...)
+		job.OutputArgs = append(append([]string{}, container.MuxerArgs...), "-map_metadata", "1", "-f", container.Muxer)
+		job.OutputArgs = append(job.OutputArgs, progressArgs...)
+		job.OutputArgs = append(job.OutputArgs, "-y", tempPath)
+		if err := c.encodeSegmented(inputPath, filename, tempPath, job); err != nil {
+			return fmt.Errorf("conversion failed: %w", err)
+		}
+	} else if err := c.encodeWhole(queued, inputPath, filename, outputPath, profile, inputArgs, videoMap, videoArgs, x265Params, streamArgs, outputArgs); err != nil {
 		return fmt.Errorf("conversion failed: %w", err)
 	}
 
 	// Verify temporary file integrity
-	if err := c.security.VerifyOutputFile(inputPath, tempPath, "video", "mp4"); err != nil {
+	if err := c.security.VerifyOutputFile(inputPath, tempPath, "video", container.Name); err != nil {
 		return fmt.Errorf("output verification failed: %w", err)
 	}
 
+	// Every mapped track and chapter must be present in the output
+	if mapping != nil {
+		if err := c.security.VerifyStreamLayout(tempPath, mapping.Expected); err != nil {
+			return fmt.Errorf("stream verification failed: %w", err)
+		}
+	}
+
+	// Flag key tags (GPS, make/model) and high-speed frame rates lost by the conversion
+	if sourceMeta != nil {
+		if output, err := utils.ProbeMedia(tempPath); err == nil {
+			if lost := diffVideoMetadata(*sourceMeta, output); len(lost) > 0 {
+				c.logger.Warn(fmt.Sprintf("🏷️  %s: metadata not preserved: %s", filename, strings.Join(lost, ", ")))
+				c.stats.mu.Lock()
+				c.stats.metadataLossFiles++
+				c.stats.mu.Unlock()
+			}
+		}
+	}
+
+	// HDR outputs must keep their transfer function and bit depth
+	if isHDR {
+		if err := c.security.VerifyHDR(tempPath, hdr.Source, hdr.Tonemapped); err != nil {
+			return fmt.Errorf("HDR verification failed: %w", err)
+		}
+	}
+
+	// Animated sources must keep every frame and their timing
+	if plan.Animation != nil {
+		if err := c.security.VerifyAnimation(tempPath, "video", *plan.Animation); err != nil {
+			return fmt.Errorf("animation verification failed: %w", err)
+		}
+	}
+
+	// Keep the original when the encode does not save enough space
+	if savings, ok := c.meetsMinimumSavings(inputPath, tempPath); !ok {
+		if joined {
+			return c.keepChapterOriginals(plan.Chapters.Group, tempPath, savings)
+		}
+		return c.keepOriginalInstead(inputPath, tempPath, "video", savings)
+	}
+
 	// Atomic move: rename temp file to final destination
 	if err := os.Rename(tempPath, outputPath); err != nil {
 		return fmt.Errorf("failed to finalize conversion: %w", err)
@@ -287,20 +566,90 @@ func (c *Converter) convertVideo(inputPath string) error {
 	c.logger.Success(logEntry)
 
 	// Update size statistics
-	c.updateSizeStats(originalSizeMB, newSizeMB)
+	c.updateSizeStats(queued, "video", normalizeVideoCodec(plan.Codec), originalSizeMB, newSizeMB)
+	if isInterlaced {
+		c.stats.mu.Lock()
+		c.stats.deinterlacedFiles++
+		c.stats.mu.Unlock()
+	}
+	if slowMotionLost {
+		c.stats.mu.Lock()
+		c.stats.slowMotionLost++
+		c.stats.mu.Unlock()
+	}
+	if isHDR {
+		c.stats.mu.Lock()
+		if hdr.Tonemapped {
+			c.stats.hdrTonemapped++
+		} else {
+			c.stats.hdrPreserved++
+		}
+		c.stats.mu.Unlock()
+	}
 
 	// Safe deletion if requested
 	if !c.config.KeepOriginals {
-		if err := c.security.SafeDelete(inputPath, outputPath); err != nil {
-			c.logger.Warn(fmt.Sprintf("Deletion cancelled for safety: %s (%v)", filename, err))
-		} else {
-			c.logger.Security(fmt.Sprintf("Safe deletion: %s", filename))
+		for _, original := range originals {
+			if err := c.security.SafeDelete(original, outputPath); err != nil {
+				c.logger.Warn(fmt.Sprintf("Deletion cancelled for safety: %s (%v)", filepath.Base(original), err))
+			} else {
+				c.logger.Security(fmt.Sprintf("Safe deletion: %s", filepath.Base(original)))
+			}
 		}
 	}
 
 	return nil
 }
 
+// encodeWhole encodes the whole file in one ffmpeg run, or two for a two-pass
+// encode, under a single timeout. The analysis pass maps the same video
+// stream as the final one (videoMap), which streamArgs already include.
+func (c *Converter) encodeWhole(queued, inputPath, filename, outputPath string, profile videoEncodingProfile, inputArgs, videoMap, videoArgs, x265Params, streamArgs, outputArgs []string) error {
+	ctx, cancel := c.encodeContext(context.Background(), queued, c.config.ConversionTimeoutVideo)
+	defer cancel()
+
+	passes := 1
+	logPrefix := outputPath + ".passlog"
+	if profile.TwoPass {
+		passes = 2
+		defer removePassLogs(logPrefix)
+	}
+
+	startTime := time.Now()
+	for pass := 1; pass <= passes; pass++ {
+		args := append([]string{}, inputArgs...)
+		args = append(args, videoArgs...)
+		passParams := x265Params
+		if profile.TwoPass {
+			extraArgs, extraParams := passArgs(profile.Codec, pass, logPrefix)
+			args = append(args, extraArgs...)
+			passParams = append(append([]string{}, x265Params...), extraParams...)
+		}
+		if len(passParams) > 0 {
+			args = append(args, "-x265-params", strings.Join(passParams, ":"))
+		}
+
+		if pass < passes {
+			// Analysis pass: video only, output discarded
+			args = append(args, videoMap...)
+			args = append(args, "-an", "-sn", "-dn", "-f", "null")
+			args = append(args, progressArgs...)
+			args = append(args, "-y", os.DevNull)
+		} else {
+			args = append(args, streamArgs...)
+			args = append(args, outputArgs...)
+		}
+
+		cmd := c.newFFmpegCommand(ctx, args...)
+
+		// Start the command and monitor progress
+		if err := c.runVideoConversionWithProgress(cmd, inputPath, filename, encodePass{Number: pass, Total: passes, Start: startTime, Job: queued, Encoder: profile.Codec}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Converter) newFFmpegCommand(ctx context.Context, args ...string) *exec.Cmd {
 	command := "ffmpeg"
 	if len(c.ffmpegCommand) > 0 {
@@ -316,30 +665,55 @@ func (c *Converter) newFFmpegCommand(ctx context.Context, args ...string) *exec.
 	return exec.CommandContext(ctx, command, cmdArgs...)
 }
 
-func (c *Converter) runVideoConversionWithProgress(cmd *exec.Cmd, inputPath, filename string) error {
-	// Create pipes for stderr (where ffmpeg sends progress)
-	stderr, err := cmd.StderrPipe()
+// encodePass identifies one ffmpeg run of a possibly multi-pass encode, so
+// progress can be reported across all passes.
+type encodePass struct {
+	Number int
+	Total  int
+	Start  time.Time
+
+	// Queued file and ffmpeg encoder, passed on to progress subscribers
+	Job     string
+	Encoder string
+}
+
+func (c *Converter) runVideoConversionWithProgress(cmd *exec.Cmd, inputPath, filename string, pass encodePass) error {
+	// Progress arrives as key=value lines on stdout; stderr only carries
+	// messages, kept for error reports
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		return fmt.Errorf("failed to create progress pipe: %w", err)
+	}
+	var stderrOutput strings.Builder
+	cmd.Stderr = &stderrOutput
+
+	progress := VideoProgress{
+		File:    filename,
+		Source:  inputPath,
+		Pass:    pass.Number,
+		Passes:  pass.Total,
+		Started: pass.Start,
+		Job:     pass.Job,
+		Encoder: pass.Encoder,
+	}
+	if info, err := os.Stat(inputPath); err == nil {
+		progress.SourceSize = info.Size()
+	}
+	if duration, err := utils.GetVideoDuration(inputPath); err == nil {
+		progress.Duration = duration
 	}
 
 	// Start the command
-	if err := cmd.Start(); err != nil {
+	detach, err := c.startProcess(pass.Job, cmd)
+	if err != nil {
 		return fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
+	defer detach()
 
-	// Monitor progress in a goroutine and capture stderr output
-	done := make(chan error, 1)
-	var stderrOutput strings.Builder
-	go func() {
-		defer stderr.Close()
-		c.monitorVideoProgress(stderr, inputPath, filename, &stderrOutput)
-		done <- cmd.Wait()
-	}()
+	c.monitorVideoProgress(stdout, progress)
+	io.Copy(io.Discard, stdout) // drain anything after progress=end
 
-	// Wait for completion
-	err = <-done
-	if err != nil {
+	if err := cmd.Wait(); err != nil {
 		// Include stderr output in error message
 		stderrText := stderrOutput.String()
 		if stderrText != "" {
@@ -350,97 +724,39 @@ func (c *Converter) runVideoConversionWithProgress(cmd *exec.Cmd, inputPath, fil
 	return nil
 }
 
-type VideoProgress struct {
-	filename      string
-	startTime     time.Time
-	totalDuration time.Duration
-	currentTime   time.Duration
-	speed         float64
-	fileSizeMB    float64
-	lastUpdate    time.Time
-	progressShown bool
-}
-
-func (c *Converter) monitorVideoProgress(reader io.Reader, inputPath, filename string, stderrBuffer *strings.Builder) {
-	scanner := bufio.NewScanner(reader)
-
-	// Get file size for cost calculation
-	var fileSizeMB float64
-	if info, err := os.Stat(inputPath); err == nil && info != nil {
-		fileSizeMB = float64(info.Size()) / (1024 * 1024)
-	} else {
-		fileSizeMB = 0 // Default to 0 if we can't get file size
-	}
-
-	progress := &VideoProgress{
-		filename:      filename,
-		startTime:     time.Now(),
-		totalDuration: 0, // Will be extracted from ffmpeg output
-		fileSizeMB:    fileSizeMB,
-		lastUpdate:    time.Now(),
-	}
-
-	// Regex to parse ffmpeg progress output
-	timeRegex := regexp.MustCompile(`time=(\d{2}):(\d{2}):(\d{2}\.\d{2})`)
-	speedRegex := regexp.MustCompile(`speed=\s*([0-9.]+)x`)
-	durationRegex := regexp.MustCompile(`Duration: (\d{2}):(\d{2}):(\d{2}\.\d{2})`)
+// monitorVideoProgress publishes every progress snapshot and logs a progress
+// line every 30 seconds (reduced frequency for parallel processing).
+func (c *Converter) monitorVideoProgress(reader io.Reader, progress VideoProgress) {
+	announced := progress.Pass > 1 // Announced by the first pass
+	lastUpdate := time.Now()
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	readProgress(reader, progress, func(snapshot VideoProgress) {
+		c.publishProgress(snapshot)
 
-		// Capture all stderr output for error reporting
-		stderrBuffer.WriteString(line + "\n")
-
-		// Parse total duration from ffmpeg output (appears early in the output)
-		if progress.totalDuration == 0 {
-			if durationMatch := durationRegex.FindStringSubmatch(line); len(durationMatch) >= 4 {
-				hours, _ := strconv.Atoi(durationMatch[1])
-				minutes, _ := strconv.Atoi(durationMatch[2])
-				seconds, _ := strconv.ParseFloat(durationMatch[3], 64)
-				progress.totalDuration = time.Duration(float64(hours*3600+minutes*60)+seconds) * time.Second
-			}
+		if !announced {
+			c.logger.Info(fmt.Sprintf("📹 %s (%.1f MB) - converting...", filepath.Base(snapshot.File), float64(snapshot.SourceSize)/(1024*1024)))
+			announced = true
 		}
-
-		// Parse current time
-		if timeMatch := timeRegex.FindStringSubmatch(line); len(timeMatch) >= 4 {
-			hours, _ := strconv.Atoi(timeMatch[1])
-			minutes, _ := strconv.Atoi(timeMatch[2])
-			seconds, _ := strconv.ParseFloat(timeMatch[3], 64)
-			progress.currentTime = time.Duration(float64(hours*3600+minutes*60)+seconds) * time.Second
+		if snapshot.Done || time.Since(lastUpdate) > 30*time.Second {
+			c.showVideoProgress(snapshot)
+			lastUpdate = time.Now()
 		}
-
-		// Parse speed
-		if speedMatch := speedRegex.FindStringSubmatch(line); len(speedMatch) >= 2 {
-			progress.speed, _ = strconv.ParseFloat(speedMatch[1], 64)
+		if snapshot.Done && snapshot.Pass == snapshot.Passes {
+			c.showVideoCompletion(snapshot)
 		}
-
-		// Show progress update every 30 seconds (reduced frequency for parallel processing)
-		if time.Since(progress.lastUpdate) > 30*time.Second {
-			c.showVideoProgress(progress)
-			progress.lastUpdate = time.Now()
-		}
-
-		// Check for completion
-		if strings.Contains(line, "progress=end") {
-			c.showVideoProgress(progress) // Final progress update
-			c.showVideoCompletion(progress)
-			return
-		}
-	}
+	})
 }
 
-// getVideoInfo function removed - duration is now extracted from ffmpeg output stream
-// This eliminates the preliminary ffprobe call for better performance
-
-func (c *Converter) showVideoProgress(progress *VideoProgress) {
-	if progress.totalDuration == 0 {
+func (c *Converter) showVideoProgress(progress VideoProgress) {
+	if progress.Duration == 0 {
 		return
 	}
+	progressPercent := progress.Percent()
 
-	// Calculate progress percentage
-	progressPercent := float64(progress.currentTime) / float64(progress.totalDuration) * 100
-	if progressPercent > 100 {
-		progressPercent = 100
+	// Multi-pass encodes report overall progress across every pass
+	passLabel := ""
+	if progress.Passes > 1 {
+		passLabel = fmt.Sprintf(", pass %d/%d", progress.Pass, progress.Passes)
 	}
 
 	// Create progress bar
@@ -448,69 +764,23 @@ func (c *Converter) showVideoProgress(progress *VideoProgress) {
 	filledWidth := int(progressPercent / 100 * float64(barWidth))
 	bar := strings.Repeat("█", filledWidth) + strings.Repeat("░", barWidth-filledWidth)
 
-	// Calculate ETA
 	eta := "--:--"
-	if progress.speed > 0 && progressPercent > 5 {
-		elapsed := time.Since(progress.startTime)
-		totalEstimated := time.Duration(float64(elapsed) / (progressPercent / 100))
-		remaining := totalEstimated - elapsed
-		if remaining > 0 {
-			eta = c.formatDuration(remaining)
-		}
+	if remaining, ok := progress.ETA(); ok {
+		eta = c.formatDuration(remaining)
 	}
 
-	// Show compact progress line with clear file identification
-	fileName := filepath.Base(progress.filename)
-	if !progress.progressShown {
-		c.logger.Info(fmt.Sprintf("📹 %s (%.1f MB) - converting...", fileName, progress.fileSizeMB))
-		progress.progressShown = true
-	}
-
-	progressLine := fmt.Sprintf("   %s: [%s] %.1f%% (%.1fx, ETA: %s)",
-		fileName, bar, progressPercent, progress.speed, eta)
+	progressLine := fmt.Sprintf("   %s: [%s] %.1f%% (%.1fx, %.0f fps, %.1f MB, ETA: %s%s)",
+		filepath.Base(progress.File), bar, progressPercent, progress.Speed, progress.FPS,
+		float64(progress.TotalSize)/(1024*1024), eta, passLabel)
 
 	c.logger.Info(progressLine)
 }
 
-func (c *Converter) showVideoCompletion(progress *VideoProgress) {
-	duration := time.Since(progress.startTime)
+func (c *Converter) showVideoCompletion(progress VideoProgress) {
+	duration := time.Since(progress.Started)
 
 	c.logger.Success(fmt.Sprintf("✅ %s completed in %s",
-		filepath.Base(progress.filename), c.formatDuration(duration)))
-}
-
-// getVideoAccelerationInfo détermine quelle accélération utiliser
-func (c *Converter) getVideoAccelerationInfo() VideoAccelerationInfo {
-	c.accelOnce.Do(func() {
-		info := VideoAccelerationInfo{
-			Available: false,
-			Message:   "Hardware acceleration not available",
-			Codec:     "libx265",
-			Preset:    "medium",
-		}
-
-		if !c.config.VideoAcceleration {
-			info.Message = "Acceleration disabled in config"
-			c.accelInfo = info
-			return
-		}
-
-		available, message := utils.CheckVideoAcceleration(c.ffmpegCommand)
-		if available {
-			info.Available = true
-			info.Message = message
-			info.Codec = "hevc_videotoolbox"
-			info.Preset = ""
-			info.HwAccelArgs = []string{"-hwaccel", "videotoolbox"}
-			info.OutputTag = "hvc1"
-		} else if message != "" {
-			info.Message = message + " - using software encoding"
-		}
-
-		c.accelInfo = info
-	})
-
-	return c.accelInfo
+		filepath.Base(progress.File), c.formatDuration(duration)))
 }
 
 func (c *Converter) calculateS3Cost(fileSizeMB float64, progressPercent float64) string {
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/sirupsen/logrus"
 )
 
@@ -22,6 +24,16 @@ type Logger struct {
 		bold   *color.Color
 	}
 	logFile *os.File
+
+	// Console lines go to sink instead of stdout while it is set
+	sinkMu sync.Mutex
+	sink   func(level, message string)
+}
+
+// IsTerminal reports whether stdout is an interactive terminal.
+func IsTerminal() bool {
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
 }
 
 func NewLogger(logPath string) (*Logger, error) {
@@ -66,10 +78,32 @@ func (l *Logger) Close() error {
 	return nil
 }
 
+// Redirect sends console lines (level and uncoloured message) to fn instead
+// of stdout, for a dashboard drawing the terminal itself. A nil fn restores
+// stdout. The log file is written either way.
+func (l *Logger) Redirect(fn func(level, message string)) {
+	l.sinkMu.Lock()
+	l.sink = fn
+	l.sinkMu.Unlock()
+}
+
+// println writes a formatted line to the console, or hands the message to
+// the redirect sink.
+func (l *Logger) println(level, formatted, message string) {
+	l.sinkMu.Lock()
+	sink := l.sink
+	l.sinkMu.Unlock()
+	if sink != nil {
+		sink(level, message)
+		return
+	}
+	fmt.Println(formatted)
+}
+
 func (l *Logger) Log(message string) {
 	timestamp := time.Now().Format("15:04:05")
 	formatted := fmt.Sprintf("[%s] %s", l.colors.blue.Sprint(timestamp), message)
-	fmt.Println(formatted)
+	l.println("log", formatted, message)
 
 	if l.logFile != nil {
 		l.logFile.WriteString(fmt.Sprintf("[%s] %s\n", timestamp, message))
@@ -79,7 +113,7 @@ func (l *Logger) Log(message string) {
 func (l *Logger) Error(message string) {
 	timestamp := time.Now().Format("15:04:05")
 	formatted := fmt.Sprintf("[ERROR %s] %s", l.colors.red.Sprint(timestamp), message)
-	fmt.Println(formatted)
+	l.println("error", formatted, message)
 
 	if l.logFile != nil {
 		l.logFile.WriteString(fmt.Sprintf("[ERROR %s] %s\n", timestamp, message))
@@ -88,7 +122,7 @@ func (l *Logger) Error(message string) {
 
 func (l *Logger) Success(message string) {
 	formatted := fmt.Sprintf("[%s] %s", l.colors.green.Sprint("✓"), message)
-	fmt.Println(formatted)
+	l.println("success", formatted, message)
 
 	if l.logFile != nil {
 		l.logFile.WriteString(fmt.Sprintf("[SUCCESS] %s\n", message))
@@ -97,7 +131,7 @@ func (l *Logger) Success(message string) {
 
 func (l *Logger) Warn(message string) {
 	formatted := fmt.Sprintf("[%s] %s", l.colors.yellow.Sprint("⚠"), message)
-	fmt.Println(formatted)
+	l.println("warn", formatted, message)
 
 	if l.logFile != nil {
 		l.logFile.WriteString(fmt.Sprintf("[WARN] %s\n", message))
@@ -106,7 +140,7 @@ func (l *Logger) Warn(message string) {
 
 func (l *Logger) Info(message string) {
 	formatted := fmt.Sprintf("[%s] %s", l.colors.cyan.Sprint("i"), message)
-	fmt.Println(formatted)
+	l.println("info", formatted, message)
 
 	if l.logFile != nil {
 		l.logFile.WriteString(fmt.Sprintf("[INFO] %s\n", message))
@@ -117,7 +151,7 @@ func (l *Logger) Security(message string) {
 	formatted := fmt.Sprintf("[%s] %s",
 		l.colors.red.Add(color.Bold).Sprint("🔒 SECURITY"),
 		message)
-	fmt.Println(formatted)
+	l.println("security", formatted, message)
 
 	if l.logFile != nil {
 		l.logFile.WriteString(fmt.Sprintf("[SECURITY] %s\n", message))
@@ -125,8 +159,10 @@ func (l *Logger) Security(message string) {
 }
 
 func (l *Logger) ShowHeader(keepOriginals bool) {
-	// Clear screen
-	fmt.Print("\033[H\033[2J")
+	// Clear screen, unless the output is redirected to a file or pipe
+	if IsTerminal() {
+		fmt.Print("\033[H\033[2J")
+	}
 
 	header := `
 ╔══════════════════════════════════════════════════════════════╗
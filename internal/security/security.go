@@ -2,6 +2,7 @@ package security
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -98,7 +99,10 @@ func (s *SecurityChecker) VerifyOutputFile(inputPath, outputPath, fileType, outp
 	case "photo":
 		return s.verifyImageIntegrity(outputPath)
 	case "video":
-		return s.verifyVideoIntegrity(outputPath)
+		if err := s.verifyVideoIntegrity(outputPath); err != nil {
+			return err
+		}
+		return s.verifyVideoGeometry(inputPath, outputPath)
 	default:
 		return fmt.Errorf("unknown file type: %s", fileType)
 	}
@@ -124,6 +128,167 @@ func (s *SecurityChecker) verifyVideoIntegrity(videoPath string) error {
 	return nil
 }
 
+// verifyVideoGeometry checks that the output is displayed with the orientation
+// and aspect ratio of its source, whether the rotation was baked into the
+// pixels or kept as a display matrix. Sources ffprobe cannot size are skipped.
+func (s *SecurityChecker) verifyVideoGeometry(inputPath, outputPath string) error {
+	source, err := utils.ProbeMedia(inputPath)
+	if err != nil {
+		return nil
+	}
+	sourceVideo := source.VideoStream()
+	if sourceVideo == nil || sourceVideo.DisplayAspectRatio() == 0 {
+		return nil
+	}
+
+	output, err := utils.ProbeMedia(outputPath)
+	if err != nil {
+		return fmt.Errorf("unable to read output video stream: %w", err)
+	}
+	outputVideo := output.VideoStream()
+	if outputVideo == nil {
+		return fmt.Errorf("output has no video stream")
+	}
+
+	// Encoders round odd sizes to even ones: allow two pixels on the short side
+	shortSide := sourceVideo.Width
+	if sourceVideo.Height < shortSide {
+		shortSide = sourceVideo.Height
+	}
+	tolerance := 0.01 + 2/float64(shortSide)
+
+	expected := sourceVideo.DisplayAspectRatio()
+	actual := outputVideo.DisplayAspectRatio()
+	if actual > 0 && math.Abs(actual-expected)/expected <= tolerance {
+		return nil
+	}
+	if (expected > 1) != (actual > 1) {
+		width, height := sourceVideo.DisplaySize()
+		outWidth, outHeight := outputVideo.DisplaySize()
+		return fmt.Errorf("orientation changed (%dx%d rotated %d° displayed as %dx%d rotated %d°)",
+			width, height, sourceVideo.Rotation(), outWidth, outHeight, outputVideo.Rotation())
+	}
+	return fmt.Errorf("display aspect ratio changed (%.3f -> %.3f)", expected, actual)
+}
+
+// VerifyAnimation checks that an animated output kept the frame count and
+// playback duration of its source.
+func (s *SecurityChecker) VerifyAnimation(outputPath, fileType string, expected utils.AnimationInfo) error {
+	var (
+		actual utils.AnimationInfo
+		err    error
+	)
+	if fileType == "video" {
+		actual, err = utils.CountVideoFrames(outputPath)
+	} else {
+		actual, err = utils.GetAnimationInfo(outputPath)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read output animation: %w", err)
+	}
+
+	// Video encoders may add or drop a single frame at the boundaries
+	frameTolerance := 0
+	if fileType == "video" {
+		frameTolerance = 1
+	}
+	if diff := actual.Frames - expected.Frames; diff > frameTolerance || -diff > frameTolerance {
+		return fmt.Errorf("animation frame count changed (%d -> %d frames)", expected.Frames, actual.Frames)
+	}
+
+	if expected.Duration > 0 && actual.Duration > 0 {
+		tolerance := expected.Duration / 20
+		if tolerance < 100*time.Millisecond {
+			tolerance = 100 * time.Millisecond
+		}
+		if diff := actual.Duration - expected.Duration; diff > tolerance || -diff > tolerance {
+			return fmt.Errorf("animation duration changed (%v -> %v)", expected.Duration, actual.Duration)
+		}
+	}
+
+	return nil
+}
+
+// VerifyStreamLayout checks that a video output holds the expected number of
+// video, audio and subtitle streams and chapters.
+func (s *SecurityChecker) VerifyStreamLayout(outputPath string, expected utils.StreamLayout) error {
+	probe, err := utils.ProbeMedia(outputPath)
+	if err != nil {
+		return fmt.Errorf("unable to read output streams: %w", err)
+	}
+
+	actual := probe.Layout()
+	if actual != expected {
+		return fmt.Errorf("stream layout changed (expected %s, got %s)", expected, actual)
+	}
+	return nil
+}
+
+// VerifyHDR checks the transfer function and bit depth of a video encoded from
+// an HDR source: tone-mapped outputs must be SDR, preserved ones must keep the
+// source transfer at 10 bits or more.
+func (s *SecurityChecker) VerifyHDR(outputPath string, source utils.HDRInfo, tonemapped bool) error {
+	actual, err := utils.GetVideoHDRInfo(outputPath)
+	if err != nil {
+		return fmt.Errorf("unable to read output colour properties: %w", err)
+	}
+
+	if tonemapped {
+		if actual.HDR() {
+			return fmt.Errorf("tone-mapped output still signals %s", actual.Transfer)
+		}
+		return nil
+	}
+	if actual.Transfer != source.Transfer {
+		return fmt.Errorf("transfer characteristics changed (%s -> %s)", source.Transfer, valueOrUnknown(actual.Transfer))
+	}
+	if actual.BitDepth > 0 && actual.BitDepth < 10 {
+		return fmt.Errorf("HDR output is only %d-bit", actual.BitDepth)
+	}
+	return nil
+}
+
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// VerifyColorProfile checks that an image output carries the ICC profile it is
+// expected to have. An empty expectation accepts untagged or sRGB outputs.
+// A non-empty expectedCICP (HDR photos) must match the output's nclx tags.
+func (s *SecurityChecker) VerifyColorProfile(outputPath, expected, expectedCICP string) error {
+	if expectedCICP != "" {
+		cicp, err := utils.GetImageCICP(outputPath)
+		if err != nil {
+			return fmt.Errorf("unable to read output nclx tags: %w", err)
+		}
+		if cicp.String() != expectedCICP {
+			return fmt.Errorf("nclx colour tags changed (%s -> %s)", expectedCICP, cicp)
+		}
+	}
+
+	actual, err := utils.GetImageColorProfile(outputPath)
+	if err != nil {
+		return fmt.Errorf("unable to read output colour profile: %w", err)
+	}
+
+	if expected == "" {
+		if actual != "" && !strings.Contains(strings.ToLower(actual), "srgb") {
+			return fmt.Errorf("untagged source produced %q output", actual)
+		}
+		return nil
+	}
+	if !strings.EqualFold(strings.TrimSpace(actual), strings.TrimSpace(expected)) {
+		if actual == "" {
+			return fmt.Errorf("colour profile %q was dropped", expected)
+		}
+		return fmt.Errorf("colour profile changed (%q -> %q)", expected, actual)
+	}
+	return nil
+}
+
 func (s *SecurityChecker) SafeDelete(filePath, outputPath string) error {
 	// Triple verification before deletion
 	outputInfo, err := os.Stat(outputPath)
@@ -279,6 +444,19 @@ func (s *SecurityChecker) isMarkerAbandoned(markerPath string) bool {
 	return true // No PID found or invalid format
 }
 
+// temporarySuffixes lists the suffixes of files only written during a conversion.
+var temporarySuffixes = []string{".tmp", ".develop.tif", ".develop.jpg", ".develop.pp3", ".color.png"}
+
+func isTemporaryArtifact(path string) bool {
+	for _, suffix := range temporarySuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	// Two-pass statistics (x264 .log/.mbtree, x265 .cutree, libaom .log)
+	return strings.Contains(filepath.Base(path), ".passlog")
+}
+
 // CleanupAbandonedFiles removes temporary and abandoned files
 func (s *SecurityChecker) CleanupAbandonedFiles(dir string) error {
 	var errors []string
@@ -289,8 +467,8 @@ func (s *SecurityChecker) CleanupAbandonedFiles(dir string) error {
 		}
 
 		if !info.IsDir() {
-			// Remove .tmp files
-			if strings.HasSuffix(path, ".tmp") {
+			// Remove .tmp files and intermediate RAW developments
+			if isTemporaryArtifact(path) {
 				if err := os.Remove(path); err != nil {
 					errors = append(errors, fmt.Sprintf("failed to remove %s: %v", path, err))
 				}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/converter"
+	"github.com/kevindurb/media-converter/internal/logger"
+)
+
+// controlTimeout bounds how long a client may take to send its command.
+const controlTimeout = 10 * time.Second
+
+// controlHelp lists the commands accepted on the control socket.
+const controlHelp = `commands:
+  status               phase, queue and worker state
+  queue                queued files in order
+  pause                stop starting new files; running files finish
+  resume               start new files again
+  promote <path>       move a queued file or folder to the front
+  concurrency <n>      convert n files at once
+  cancel <path>        cancel a queued or running file`
+
+// Control accepts one command per connection on a unix socket and writes
+// back its result, so the queue can be driven with the control subcommand
+// or any client such as nc -U.
+type Control struct {
+	path     string
+	conv     *converter.Converter
+	logger   *logger.Logger
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+func NewControl(path string, conv *converter.Converter, log *logger.Logger) *Control {
+	return &Control{path: path, conv: conv, logger: log}
+}
+
+// Start creates the socket, replacing a stale one left by a crashed run, and
+// serves commands in the background. The socket is only accessible to the
+// current user.
+func (c *Control) Start() error {
+	if _, err := os.Stat(c.path); err == nil {
+		if conn, err := net.DialTimeout("unix", c.path, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("control socket %s is already in use", c.path)
+		}
+		os.Remove(c.path)
+	}
+
+	listener, err := net.Listen("unix", c.path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(c.path, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict control socket: %w", err)
+	}
+	c.listener = listener
+	c.logger.Info(fmt.Sprintf("🎛️  Control socket on %s", c.path))
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					c.logger.Error(fmt.Sprintf("Control socket stopped: %v", err))
+				}
+				return
+			}
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				c.handle(conn)
+			}()
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops accepting commands and removes the socket.
+func (c *Control) Shutdown() {
+	c.listener.Close()
+	c.wg.Wait()
+	os.Remove(c.path)
+}
+
+func (c *Control) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && line == "" {
+		return
+	}
+	fmt.Fprintln(conn, c.execute(strings.TrimSpace(line)))
+}
+
+// execute runs a command line and returns the reply, prefixed with "error:"
+// when the command failed.
+func (c *Control) execute(line string) string {
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(command) {
+	case "status":
+		return formatStatus(c.conv.Status())
+	case "queue":
+		queue := c.conv.Queue()
+		if len(queue) == 0 {
+			return "queue is empty"
+		}
+		return strings.Join(queue, "\n")
+	case "pause":
+		c.conv.Pause()
+		return "paused"
+	case "resume":
+		c.conv.Resume()
+		return "resumed"
+	case "promote":
+		if arg == "" {
+			return "error: promote needs a file or folder"
+		}
+		return fmt.Sprintf("promoted %d queued files", c.conv.Promote(arg))
+	case "concurrency":
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			return "error: concurrency needs a number"
+		}
+		applied, err := c.conv.SetConcurrency(limit)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return fmt.Sprintf("concurrency set to %d", applied)
+	case "cancel":
+		if arg == "" {
+			return "error: cancel needs a file"
+		}
+		if err := c.conv.Cancel(arg); err != nil {
+			return "error: " + err.Error()
+		}
+		return "cancelling " + arg
+	case "help", "":
+		return controlHelp
+	default:
+		return fmt.Sprintf("error: unknown command %q\n%s", command, controlHelp)
+	}
+}
+
+func formatStatus(status converter.RunStatus) string {
+	phase := status.Phase
+	if phase == "" {
+		phase = "preparing"
+	}
+	state := "running"
+	switch {
+	case status.Paused:
+		state = "paused"
+	case status.Suspended:
+		state = "suspended outside the run windows"
+	case status.OutsideWindow:
+		state = "waiting for a run window"
+	}
+	done := status.Stats.ProcessedFiles + status.Stats.FailedFiles + status.Stats.CancelledFiles
+	return fmt.Sprintf("%s (%s): %d/%d files done, %d failed, %d queued, %d active (limit %d of %d)",
+		phase, state, done, status.Stats.TotalFiles, status.Stats.FailedFiles,
+		status.Queued, status.Limiter.Active, status.Limiter.Limit, status.Limiter.MaxLimit)
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kevindurb/media-converter/internal/converter"
+	"github.com/kevindurb/media-converter/internal/logger"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// shutdownTimeout bounds how long in-flight requests may take once the
+// conversion is over.
+const shutdownTimeout = 5 * time.Second
+
+// maxRequestBody bounds the JSON body of the control endpoints.
+const maxRequestBody = 64 << 10
+
+// Server exposes the run status as JSON and Prometheus metrics, and serves
+// the web dashboard. The endpoints are unauthenticated, so it should stay on
+// a local address.
+type Server struct {
+	addr     string
+	hosts    map[string]bool // names accepted in the Host header besides IPs and localhost
+	conv     *converter.Converter
+	logger   *logger.Logger
+	http     *http.Server
+	listener net.Listener
+}
+
+// New creates a server for addr. An address without a host (":8080") listens
+// on 127.0.0.1 only; 0.0.0.0 must be given to listen on every interface.
+func New(addr string, conv *converter.Converter, log *logger.Logger) *Server {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	s := &Server{addr: addr, hosts: serverNames(addr), conv: conv, logger: log}
+	s.http = &http.Server{
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start binds the address and serves requests in the background.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	if !isLoopback(s.addr) {
+		s.logger.Security(fmt.Sprintf("HTTP API on %s is reachable from the network and has no authentication", s.addr))
+	}
+	s.logger.Info(fmt.Sprintf("🌐 Status dashboard on http://%s/", listener.Addr()))
+
+	go func() {
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error(fmt.Sprintf("HTTP server stopped: %v", err))
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the server, letting in-flight requests finish.
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	s.http.Shutdown(ctx)
+}
+
+// request is the JSON body of the control endpoints.
+type request struct {
+	Path  string `json:"path"`
+	Limit int    `json:"limit"`
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	web, _ := fs.Sub(webFiles, "web")
+	mux.Handle("/", http.FileServer(http.FS(web)))
+
+	mux.HandleFunc("/api/status", get(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.conv.Status())
+	}))
+	mux.HandleFunc("/api/jobs", get(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.conv.Status().Jobs)
+	}))
+	mux.HandleFunc("/api/stats", get(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.conv.Stats())
+	}))
+	mux.HandleFunc("/api/history", get(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.conv.History())
+	}))
+	mux.HandleFunc("/api/queue", get(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.conv.Queue())
+	}))
+
+	mux.HandleFunc("/metrics", get(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.conv.WriteMetrics(w)
+	}))
+
+	mux.HandleFunc("/api/pause", post(func(w http.ResponseWriter, r *http.Request, req request) {
+		s.conv.Pause()
+		writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+	}))
+	mux.HandleFunc("/api/resume", post(func(w http.ResponseWriter, r *http.Request, req request) {
+		s.conv.Resume()
+		writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+	}))
+	mux.HandleFunc("/api/promote", post(func(w http.ResponseWriter, r *http.Request, req request) {
+		if req.Path == "" {
+			writeError(w, http.StatusBadRequest, "missing path")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"promoted": s.conv.Promote(req.Path)})
+	}))
+	mux.HandleFunc("/api/concurrency", post(func(w http.ResponseWriter, r *http.Request, req request) {
+		applied, err := s.conv.SetConcurrency(req.Limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"limit": applied})
+	}))
+	mux.HandleFunc("/api/cancel", post(func(w http.ResponseWriter, r *http.Request, req request) {
+		if req.Path == "" {
+			writeError(w, http.StatusBadRequest, "missing path")
+			return
+		}
+		if err := s.conv.Cancel(req.Path); err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"cancelled": req.Path})
+	}))
+
+	return s.checkHost(mux)
+}
+
+// checkHost rejects requests whose Host header names another server, so a
+// page whose domain is rebound to this address cannot read or drive the API.
+func (s *Server) checkHost(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, http.StatusForbidden, "unknown host "+r.Host)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost accepts IP addresses, localhost and the names of this machine.
+func (s *Server) allowedHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if host == "localhost" || net.ParseIP(host) != nil {
+		return true
+	}
+	return s.hosts[host]
+}
+
+// serverNames returns the host names the server answers to: the host of the
+// listen address and the machine's host name, bare and with .local.
+func serverNames(addr string) map[string]bool {
+	names := make(map[string]bool)
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		names[strings.ToLower(host)] = true
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hostname = strings.ToLower(hostname)
+		short, _, _ := strings.Cut(hostname, ".")
+		names[hostname] = true
+		names[short] = true
+		names[short+".local"] = true
+	}
+	return names
+}
+
+// get rejects requests using another method.
+func get(handler http.HandlerFunc) http.HandlerFunc {
+	return method(http.MethodGet, handler)
+}
+
+// post rejects requests using another method, and requests a cross-site page
+// can send without a CORS preflight: the body must be application/json and a
+// browser Origin must match the Host.
+func post(handler func(http.ResponseWriter, *http.Request, request)) http.HandlerFunc {
+	return method(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+			writeError(w, http.StatusForbidden, "cross-origin request rejected")
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+			return
+		}
+
+		var req request
+		decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+		handler(w, r, req)
+	})
+}
+
+// sameOrigin reports whether a browser Origin header points at host.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, host)
+}
+
+func method(name string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != name {
+			w.Header().Set("Allow", name)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// isLoopback reports whether an address only accepts local connections.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevindurb/media-converter/internal/config"
+	"github.com/kevindurb/media-converter/internal/converter"
+	"github.com/kevindurb/media-converter/internal/logger"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "conversion.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv := converter.NewConverter(&config.Config{SourceDir: t.TempDir(), DestDir: t.TempDir()}, log)
+	return New(":8080", conv, log)
+}
+
+// serve sends a request to the server's handler as a local client would.
+func serve(s *Server, method, target, contentType, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "127.0.0.1:8080"
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestNewBindsLoopbackByDefault(t *testing.T) {
+	if s := newTestServer(t); s.addr != "127.0.0.1:8080" {
+		t.Errorf("expected :8080 to bind 127.0.0.1:8080, got %s", s.addr)
+	}
+}
+
+func TestRoutesMethods(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/api/status", http.StatusOK},
+		{http.MethodGet, "/api/jobs", http.StatusOK},
+		{http.MethodGet, "/api/stats", http.StatusOK},
+		{http.MethodGet, "/api/history", http.StatusOK},
+		{http.MethodGet, "/api/queue", http.StatusOK},
+		{http.MethodGet, "/metrics", http.StatusOK},
+		{http.MethodGet, "/", http.StatusOK},
+		{http.MethodPost, "/api/status", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/pause", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/cancel", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/pause", http.StatusOK},
+		{http.MethodPost, "/api/resume", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serve(s, tt.method, tt.target, "application/json", "", nil)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d (%s)", tt.method, tt.target, tt.want, w.Code, w.Body)
+		}
+		if w.Code == http.StatusMethodNotAllowed && w.Header().Get("Allow") == "" {
+			t.Errorf("%s %s: missing Allow header", tt.method, tt.target)
+		}
+	}
+}
+
+func TestControlRequests(t *testing.T) {
+	s := newTestServer(t)
+
+	w := serve(s, http.MethodPost, "/api/concurrency", "application/json", `{"limit": 2}`, nil)
+	var applied map[string]int
+	if err := json.NewDecoder(w.Body).Decode(&applied); err != nil || w.Code != http.StatusOK || applied["limit"] != 2 {
+		t.Errorf("expected the limit set to 2, got %d %v (%v)", w.Code, applied, err)
+	}
+
+	tests := []struct {
+		name, target, body string
+		want               int
+	}{
+		{"invalid limit", "/api/concurrency", `{"limit": 0}`, http.StatusBadRequest},
+		{"unknown field", "/api/concurrency", `{"limit": 2, "extra": 1}`, http.StatusBadRequest},
+		{"missing path", "/api/cancel", `{}`, http.StatusBadRequest},
+		{"unknown file", "/api/cancel", `{"path": "missing.mov"}`, http.StatusNotFound},
+		{"promote", "/api/promote", `{"path": "holiday"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := serve(s, http.MethodPost, tt.target, "application/json", tt.body, nil); w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d (%s)", tt.name, tt.want, w.Code, w.Body)
+		}
+	}
+}
+
+func TestCrossSiteRequestsRejected(t *testing.T) {
+	s := newTestServer(t)
+
+	// A cross-site form can send these without a CORS preflight
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		if w := serve(s, http.MethodPost, "/api/pause", contentType, `{}`, nil); w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("content type %q: expected 415, got %d", contentType, w.Code)
+		}
+	}
+
+	w := serve(s, http.MethodPost, "/api/pause", "application/json", `{}`, map[string]string{"Origin": "http://evil.example"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("foreign origin: expected 403, got %d", w.Code)
+	}
+	w = serve(s, http.MethodPost, "/api/pause", "application/json", `{}`, map[string]string{"Origin": "http://127.0.0.1:8080"})
+	if w.Code != http.StatusOK {
+		t.Errorf("same origin: expected 200, got %d (%s)", w.Code, w.Body)
+	}
+	if s.conv.Status().Paused {
+		s.conv.Resume()
+	} else {
+		t.Error("the same-origin pause was not applied")
+	}
+
+	// DNS rebinding: a foreign name resolved to this address
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.Host = "evil.example:8080"
+	rec := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("foreign host: expected 403, got %d", rec.Code)
+	}
+	for _, host := range []string{"localhost:8080", "[::1]:8080", "192.168.1.10:8080"} {
+		if !s.allowedHost(host) {
+			t.Errorf("expected host %s to be allowed", host)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"debug/macho"
 	"errors"
 	"fmt"
@@ -8,8 +9,12 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 )
 
+// encoderProbeTimeout bounds the one-frame test encode of a hardware encoder.
+const encoderProbeTimeout = 20 * time.Second
+
 // ResolveFFmpegCommand returns the ffmpeg command (with optional prefix) to run.
 // On Apple Silicon systems we try to prefer the native arm64 Homebrew binary
 // because Rosetta builds are significantly slower for video encoding.
@@ -58,32 +63,68 @@ func ResolveFFmpegCommand() ([]string, string) {
 	return defaultCmd, "ffmpeg not found; install it to enable video conversion"
 }
 
-// CheckVideoAcceleration verifies whether hardware acceleration is available
-// for the configured ffmpeg command.
-func CheckVideoAcceleration(ffmpegCmd []string) (bool, string) {
-	if runtime.GOOS == "darwin" {
-		return checkVideoToolbox(ffmpegCmd)
+// ProbeFFmpegEncoder encodes one synthetic frame with encoder to confirm the
+// device behind a hardware encoder works; listing it in `ffmpeg -encoders`
+// only means it was compiled in. initArgs set up the device and filters
+// upload the frame when the encoder needs hardware surfaces.
+func ProbeFFmpegEncoder(ffmpegCmd []string, initArgs []string, encoder, filters string) error {
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	args = append(args, initArgs...)
+	args = append(args, "-f", "lavfi", "-i", "color=c=black:s=256x256:d=0.1", "-frames:v", "1")
+	if filters != "" {
+		args = append(args, "-vf", filters)
+	}
+	args = append(args, "-c:v", encoder, "-f", "null", "-")
+
+	ctx, cancel := context.WithTimeout(context.Background(), encoderProbeTimeout)
+	defer cancel()
+
+	command, cmdArgs := ffmpegInvocation(ffmpegCmd, args)
+	if output, err := exec.CommandContext(ctx, command, cmdArgs...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s probe failed: %w (%s)", encoder, err, strings.TrimSpace(string(output)))
 	}
-	return false, "Hardware acceleration not supported on this platform"
+	return nil
 }
 
-// checkVideoToolbox verifies that the VideoToolbox H.265 encoder is available.
-func checkVideoToolbox(ffmpegCmd []string) (bool, string) {
+// ListFFmpegEncoders returns the names of the encoders compiled into ffmpeg.
+func ListFFmpegEncoders(ffmpegCmd []string) (map[string]bool, error) {
 	output, err := runFFmpegCommand(ffmpegCmd, "-hide_banner", "-encoders")
 	if err != nil {
-		return false, "FFmpeg not found or error checking encoders"
+		return nil, fmt.Errorf("failed to list ffmpeg encoders: %w", err)
 	}
+	return ParseFFmpegEncoders(string(output)), nil
+}
 
-	outputStr := string(output)
-	if strings.Contains(outputStr, "hevc_videotoolbox") {
-		return true, "VideoToolbox H.265 encoder available"
+// ParseFFmpegEncoders extracts encoder names from `ffmpeg -encoders` output,
+// skipping the legend printed before the dashed separator.
+func ParseFFmpegEncoders(output string) map[string]bool {
+	encoders := make(map[string]bool)
+	listing := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !listing {
+			listing = strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
 	}
-
-	return false, "VideoToolbox H.265 encoder not available"
+	return encoders
 }
 
 // runFFmpegCommand executes ffmpeg (with optional prefix command) and returns the output.
 func runFFmpegCommand(ffmpegCmd []string, args ...string) ([]byte, error) {
+	command, cmdArgs := ffmpegInvocation(ffmpegCmd, args)
+	return exec.Command(command, cmdArgs...).Output()
+}
+
+// ffmpegInvocation splits the ffmpeg command (with optional prefix) into the
+// program and its arguments.
+func ffmpegInvocation(ffmpegCmd []string, args []string) (string, []string) {
 	command := "ffmpeg"
 	if len(ffmpegCmd) > 0 {
 		command = ffmpegCmd[0]
@@ -93,9 +134,7 @@ func runFFmpegCommand(ffmpegCmd []string, args ...string) ([]byte, error) {
 	if len(ffmpegCmd) > 1 {
 		cmdArgs = append(cmdArgs, ffmpegCmd[1:]...)
 	}
-	cmdArgs = append(cmdArgs, args...)
-
-	return exec.Command(command, cmdArgs...).Output()
+	return command, append(cmdArgs, args...)
 }
 
 // findFFmpegInPath returns the first ffmpeg binary found in PATH.
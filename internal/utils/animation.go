@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnimationInfo describes the frame count and total playback time of a source.
+type AnimationInfo struct {
+	Frames   int
+	Duration time.Duration
+}
+
+// Animated reports whether the source holds more than one frame.
+func (a AnimationInfo) Animated() bool {
+	return a.Frames > 1
+}
+
+// GetAnimationInfo counts frames and sums frame delays of an image. PNG files
+// are read through ffprobe because ImageMagick only sees the first APNG frame.
+func GetAnimationInfo(filePath string) (AnimationInfo, error) {
+	if HasExtension(filePath, []string{"png", "apng"}) {
+		return CountVideoFrames(filePath)
+	}
+
+	cmd := exec.Command("magick", "identify", "-format", "%T\n", filePath)
+	output, err := cmd.Output()
+	if err != nil {
+		return AnimationInfo{}, fmt.Errorf("identify failed: %w", err)
+	}
+
+	info := AnimationInfo{}
+	var centiseconds int
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		info.Frames++
+		if delay, err := strconv.Atoi(line); err == nil {
+			centiseconds += delay
+		}
+	}
+	info.Duration = time.Duration(centiseconds) * 10 * time.Millisecond
+
+	return info, nil
+}
+
+// CountVideoFrames decodes the primary video stream with ffprobe and returns
+// the exact number of frames together with the container duration.
+func CountVideoFrames(filePath string) (AnimationInfo, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-count_frames",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=nb_read_frames:format=duration",
+		"-print_format", "json",
+		filePath,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return AnimationInfo{}, fmt.Errorf("ffprobe frame count failed: %w", err)
+	}
+
+	var result struct {
+		Streams []struct {
+			NbReadFrames string `json:"nb_read_frames"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return AnimationInfo{}, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+	if len(result.Streams) == 0 {
+		return AnimationInfo{}, fmt.Errorf("no video stream found")
+	}
+
+	info := AnimationInfo{}
+	info.Frames, _ = strconv.Atoi(result.Streams[0].NbReadFrames)
+	if seconds, err := strconv.ParseFloat(result.Format.Duration, 64); err == nil {
+		info.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	return info, nil
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MediaAttributes summarises the source characteristics used by routing rules.
+type MediaAttributes struct {
+	Width       int
+	Height      int
+	BitDepth    int
+	HasAlpha    bool
+	Frames      int
+	CameraModel string
+}
+
+// Animated reports whether the source holds more than one frame.
+func (a MediaAttributes) Animated() bool {
+	return a.Frames > 1
+}
+
+// GetImageAttributes reads dimensions, depth, alpha, frame count and camera
+// model from the first frame of an image using ImageMagick.
+func GetImageAttributes(filePath string) (MediaAttributes, error) {
+	cmd := exec.Command("magick", "identify",
+		"-format", "%w|%h|%z|%A|%n|%[EXIF:Model]\n",
+		filePath)
+	output, err := cmd.Output()
+	if err != nil {
+		return MediaAttributes{}, fmt.Errorf("identify failed: %w", err)
+	}
+
+	line := strings.SplitN(strings.TrimSpace(string(output)), "\n", 2)[0]
+	fields := strings.SplitN(line, "|", 6)
+	if len(fields) < 5 {
+		return MediaAttributes{}, fmt.Errorf("unexpected identify output: %q", line)
+	}
+
+	attrs := MediaAttributes{}
+	attrs.Width, _ = strconv.Atoi(fields[0])
+	attrs.Height, _ = strconv.Atoi(fields[1])
+	attrs.BitDepth, _ = strconv.Atoi(fields[2])
+	alpha := strings.ToLower(strings.TrimSpace(fields[3]))
+	attrs.HasAlpha = alpha != "" && alpha != "false" && alpha != "undefined"
+	attrs.Frames, _ = strconv.Atoi(fields[4])
+	if len(fields) == 6 {
+		attrs.CameraModel = strings.TrimSpace(fields[5])
+	}
+
+	return attrs, nil
+}
+
+// GetVideoAttributes derives the same attributes from an ffprobe inventory.
+func GetVideoAttributes(filePath string) (MediaAttributes, error) {
+	probe, err := ProbeMedia(filePath)
+	if err != nil {
+		return MediaAttributes{}, err
+	}
+
+	stream := probe.VideoStream()
+	if stream == nil {
+		return MediaAttributes{}, fmt.Errorf("no video stream found")
+	}
+
+	pixFmt := strings.ToLower(stream.PixFmt)
+	attrs := MediaAttributes{
+		Width:    stream.Width,
+		Height:   stream.Height,
+		BitDepth: stream.BitDepth(),
+		HasAlpha: strings.HasPrefix(pixFmt, "yuva") || strings.Contains(pixFmt, "rgba") || strings.Contains(pixFmt, "argb"),
+		Frames:   2, // Videos are always treated as animated content
+	}
+
+	for _, key := range []string{"com.apple.quicktime.model", "model"} {
+		if model := probe.Tag(key); model != "" {
+			attrs.CameraModel = model
+			break
+		}
+	}
+
+	return attrs, nil
+}
+
+// GetImageFormat returns ImageMagick's name for the encoded format of the
+// first frame (JPEG, PNG, WEBP, AVIF, HEIC...).
+func GetImageFormat(filePath string) (string, error) {
+	cmd := exec.Command("magick", "identify", "-format", "%m\n", filePath)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("identify failed: %w", err)
+	}
+
+	format := strings.SplitN(strings.TrimSpace(string(output)), "\n", 2)[0]
+	if format == "" {
+		return "", fmt.Errorf("unknown image format")
+	}
+	return strings.ToUpper(format), nil
+}
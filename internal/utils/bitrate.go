@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseBitrate converts ffmpeg-style bitrates such as "800k", "4M" or
+// "2500000" to bits per second.
+func ParseBitrate(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty bitrate")
+	}
+
+	multiplier := 1.0
+	switch value[len(value)-1] {
+	case 'k', 'K':
+		multiplier = 1_000
+		value = value[:len(value)-1]
+	case 'm', 'M':
+		multiplier = 1_000_000
+		value = value[:len(value)-1]
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", value)
+	}
+	return int64(number * multiplier), nil
+}
+
+// FormatBitrate renders a bitrate in kilobits for ffmpeg arguments.
+func FormatBitrate(bitsPerSecond int64) string {
+	return fmt.Sprintf("%dk", bitsPerSecond/1000)
+}
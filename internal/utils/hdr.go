@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// HDRInfo describes the colour signalling and static HDR metadata of a video.
+type HDRInfo struct {
+	Transfer  string
+	Primaries string
+	Matrix    string
+	Range     string
+	BitDepth  int
+
+	// MasteringDisplay and ContentLight use the x265 parameter syntax
+	MasteringDisplay string
+	ContentLight     string
+
+	// DolbyVisionProfile is non-zero when a Dolby Vision configuration is present
+	DolbyVisionProfile int
+}
+
+// HDR reports whether the transfer function is PQ or HLG.
+func (h HDRInfo) HDR() bool {
+	return h.PQ() || h.HLG()
+}
+
+// PQ reports whether the video uses the SMPTE ST 2084 transfer (HDR10).
+func (h HDRInfo) PQ() bool {
+	return h.Transfer == "smpte2084"
+}
+
+// HLG reports whether the video uses the ARIB STD-B67 transfer (HLG).
+func (h HDRInfo) HLG() bool {
+	return h.Transfer == "arib-std-b67"
+}
+
+// GetVideoHDRInfo reads the colour properties of the primary video stream and
+// the static HDR metadata attached to the stream or its first frame.
+func GetVideoHDRInfo(filePath string) (HDRInfo, error) {
+	probe, err := ProbeMedia(filePath)
+	if err != nil {
+		return HDRInfo{}, err
+	}
+	stream := probe.VideoStream()
+	if stream == nil {
+		return HDRInfo{}, fmt.Errorf("no video stream found")
+	}
+
+	info := HDRInfo{
+		Transfer:  stream.ColorTransfer,
+		Primaries: stream.ColorPrimaries,
+		Matrix:    stream.ColorSpace,
+		Range:     stream.ColorRange,
+		BitDepth:  stream.BitDepth(),
+	}
+	if !info.HDR() {
+		return info, nil
+	}
+
+	sideData := append([]ProbeSideData{}, stream.SideDataList...)
+	if frameData, err := probeFirstFrameSideData(filePath); err == nil {
+		sideData = append(sideData, frameData...)
+	}
+	for _, entry := range sideData {
+		switch entry.Type() {
+		case "Mastering display metadata":
+			if info.MasteringDisplay == "" {
+				info.MasteringDisplay = formatMasteringDisplay(entry)
+			}
+		case "Content light level metadata":
+			if info.ContentLight == "" {
+				info.ContentLight = fmt.Sprintf("%s,%s", entry.String("max_content"), entry.String("max_average"))
+			}
+		case "DOVI configuration record":
+			info.DolbyVisionProfile, _ = strconv.Atoi(entry.String("dv_profile"))
+		}
+	}
+
+	return info, nil
+}
+
+func probeFirstFrameSideData(filePath string) ([]ProbeSideData, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-read_intervals", "%+#1",
+		"-show_entries", "frame=side_data_list",
+		"-print_format", "json",
+		filePath,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe frame side data failed: %w", err)
+	}
+
+	var result struct {
+		Frames []struct {
+			SideDataList []ProbeSideData `json:"side_data_list"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+	if len(result.Frames) == 0 {
+		return nil, nil
+	}
+	return result.Frames[0].SideDataList, nil
+}
+
+// formatMasteringDisplay converts ffprobe's rational mastering display values
+// into x265's master-display string (chromaticity in 0.00002, luminance in
+// 0.0001 cd/m² units).
+func formatMasteringDisplay(entry ProbeSideData) string {
+	chroma := func(key string) int64 {
+		return int64(math.Round(ParseRational(entry.String(key)) * 50000))
+	}
+	luminance := func(key string) int64 {
+		return int64(math.Round(ParseRational(entry.String(key)) * 10000))
+	}
+	if entry.String("red_x") == "" || entry.String("max_luminance") == "" {
+		return ""
+	}
+	return fmt.Sprintf("G(%d,%d)B(%d,%d)R(%d,%d)WP(%d,%d)L(%d,%d)",
+		chroma("green_x"), chroma("green_y"),
+		chroma("blue_x"), chroma("blue_y"),
+		chroma("red_x"), chroma("red_y"),
+		chroma("white_point_x"), chroma("white_point_y"),
+		luminance("max_luminance"), luminance("min_luminance"))
+}
+
+// Auxiliary image types of HDR gain maps stored in HEIF/AVIF containers.
+var gainMapMarkers = [][]byte{
+	[]byte("urn:com:apple:photo:2020:aux:hdrgainmap"),
+	[]byte("urn:com:photo:aux:hdrgainmap"),
+	[]byte("tmap"),
+}
+
+// HasGainMap reports whether a HEIF/AVIF image declares an HDR gain map. The
+// declaration lives in the metadata box at the start of the file.
+func HasGainMap(filePath string) (bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 64*1024)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	for _, marker := range gainMapMarkers {
+		if bytes.Contains(head[:n], marker) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Transfer characteristics of HDR images (ITU-T H.273)
+const (
+	TransferPQ  = 16
+	TransferHLG = 18
+)
+
+// ImageCICP holds the nclx colour signalling of a HEIF/AVIF image as ITU-T
+// H.273 code points. Zero values are unknown.
+type ImageCICP struct {
+	Primaries int
+	Transfer  int
+	Matrix    int
+}
+
+// HDR reports whether the image uses the PQ or HLG transfer.
+func (c ImageCICP) HDR() bool {
+	return c.Transfer == TransferPQ || c.Transfer == TransferHLG
+}
+
+// String returns the primaries/transfer/matrix triplet used by avifenc --cicp.
+func (c ImageCICP) String() string {
+	return fmt.Sprintf("%d/%d/%d", c.Primaries, c.Transfer, c.Matrix)
+}
+
+// ffprobe names of the H.273 code points met in photos
+var (
+	cicpPrimaries = map[string]int{"bt709": 1, "unknown": 2, "bt470bg": 5, "smpte170m": 6, "bt2020": 9, "smpte431": 11, "smpte432": 12}
+	cicpTransfers = map[string]int{"bt709": 1, "unknown": 2, "smpte170m": 6, "linear": 8, "iec61966-2-1": 13, "bt2020-10": 14, "bt2020-12": 15, "smpte2084": 16, "arib-std-b67": 18}
+	cicpMatrices  = map[string]int{"gbr": 0, "bt709": 1, "unknown": 2, "bt470bg": 5, "smpte170m": 6, "bt2020nc": 9, "bt2020c": 10}
+)
+
+// GetImageCICP reads the nclx colour box of a HEIF/AVIF image with exiftool,
+// falling back to ffprobe when exiftool is missing or finds none.
+func GetImageCICP(filePath string) (ImageCICP, error) {
+	output, err := exec.Command("exiftool", "-j", "-n", "-ColorPrimaries", "-TransferCharacteristics", "-MatrixCoefficients", filePath).Output()
+	if err == nil {
+		if cicp, err := parseExiftoolCICP(output); err == nil {
+			return cicp, nil
+		}
+	}
+
+	probe, err := ProbeMedia(filePath)
+	if err != nil {
+		return ImageCICP{}, err
+	}
+	stream := probe.VideoStream()
+	if stream == nil || stream.ColorTransfer == "" {
+		return ImageCICP{}, fmt.Errorf("no nclx colour information found")
+	}
+	return ImageCICP{
+		Primaries: cicpPrimaries[stream.ColorPrimaries],
+		Transfer:  cicpTransfers[stream.ColorTransfer],
+		Matrix:    cicpMatrices[stream.ColorSpace],
+	}, nil
+}
+
+func parseExiftoolCICP(output []byte) (ImageCICP, error) {
+	var result []struct {
+		ColorPrimaries          *int
+		TransferCharacteristics *int
+		MatrixCoefficients      *int
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return ImageCICP{}, fmt.Errorf("invalid exiftool output: %w", err)
+	}
+	if len(result) == 0 || result[0].TransferCharacteristics == nil {
+		return ImageCICP{}, fmt.Errorf("no nclx colour information found")
+	}
+	cicp := ImageCICP{Transfer: *result[0].TransferCharacteristics}
+	if result[0].ColorPrimaries != nil {
+		cicp.Primaries = *result[0].ColorPrimaries
+	}
+	if result[0].MatrixCoefficients != nil {
+		cicp.Matrix = *result[0].MatrixCoefficients
+	}
+	return cicp, nil
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// Directories searched for the named ICC profiles (srgb, p3).
+var iccProfileDirs = []string{
+	"/usr/share/color/icc",
+	"/usr/share/color/icc/colord",
+	"/usr/share/color/icc/ghostscript",
+	"/usr/local/share/color/icc",
+	"/Library/ColorSync/Profiles",
+	"/System/Library/ColorSync/Profiles",
+}
+
+// Well-known file names of the named ICC profiles.
+var iccProfileNames = map[string][]string{
+	"srgb": {"sRGB.icc", "sRGB.icm", "sRGB Profile.icc", "srgb.icc", "sRGB-IEC61966-2.1.icc"},
+	"p3":   {"Display P3.icc", "DisplayP3.icc", "Display-P3.icc", "P3-D65.icc"},
+}
+
+// FindICCProfile resolves a profile name (srgb, p3) or a file path to an ICC
+// profile on disk.
+func FindICCProfile(name string) (string, error) {
+	candidates, named := iccProfileNames[strings.ToLower(name)]
+	if !named {
+		if _, err := os.Stat(name); err != nil {
+			return "", fmt.Errorf("ICC profile %s not found: %w", name, err)
+		}
+		return name, nil
+	}
+
+	dirs := iccProfileDirs
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append([]string{filepath.Join(home, ".local", "share", "icc"), filepath.Join(home, ".color", "icc")}, dirs...)
+	}
+	for _, dir := range dirs {
+		for _, candidate := range candidates {
+			path := filepath.Join(dir, candidate)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no %s ICC profile found in %s", name, strings.Join(dirs, ", "))
+}
+
+// ReadICCDescription returns the profile description ('desc' tag) of an ICC
+// profile file. Both ICC v2 (textDescriptionType) and v4 (mluc) are supported.
+func ReadICCDescription(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return ParseICCDescription(data)
+}
+
+// ParseICCDescription extracts the description from raw ICC profile data.
+func ParseICCDescription(data []byte) (string, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return "", fmt.Errorf("not an ICC profile")
+	}
+
+	count := int(binary.BigEndian.Uint32(data[128:132]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(data) {
+			break
+		}
+		if string(data[entry:entry+4]) != "desc" {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+4 : entry+8]))
+		size := int(binary.BigEndian.Uint32(data[entry+8 : entry+12]))
+		if offset+size > len(data) || size < 12 {
+			return "", fmt.Errorf("truncated description tag")
+		}
+		return parseICCText(data[offset : offset+size])
+	}
+	return "", fmt.Errorf("profile has no description")
+}
+
+func parseICCText(tag []byte) (string, error) {
+	switch string(tag[0:4]) {
+	case "desc":
+		length := int(binary.BigEndian.Uint32(tag[8:12]))
+		if 12+length > len(tag) {
+			return "", fmt.Errorf("truncated description text")
+		}
+		return strings.TrimRight(string(tag[12:12+length]), "\x00"), nil
+	case "mluc":
+		if len(tag) < 28 {
+			return "", fmt.Errorf("truncated description text")
+		}
+		// The first record is used; profiles list their primary language first
+		length := int(binary.BigEndian.Uint32(tag[20:24]))
+		offset := int(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+length > len(tag) {
+			return "", fmt.Errorf("truncated description text")
+		}
+		units := make([]uint16, length/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[offset+i*2:])
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00"), nil
+	default:
+		return "", fmt.Errorf("unsupported description type %q", tag[0:4])
+	}
+}
+
+// GetImageColorProfile returns the description of the ICC profile embedded in
+// the first frame of an image, or an empty string when it carries none.
+func GetImageColorProfile(filePath string) (string, error) {
+	cmd := exec.Command("magick", "identify", "-format", "%[icc:description]\n", filePath+"[0]")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("identify failed: %w", err)
+	}
+	return strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0]), nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// IsFastStart reports whether an MP4/MOV file stores its moov atom before the
+// media data, which lets players start streaming without reading the whole file.
+func IsFastStart(filePath string) (bool, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 16)
+	var offset int64
+	for {
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			if err == io.EOF {
+				return false, fmt.Errorf("no moov atom found")
+			}
+			return false, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:8])
+
+		switch boxType {
+		case "moov":
+			return true, nil
+		case "mdat":
+			return false, nil
+		}
+
+		switch size {
+		case 0:
+			// Box extends to end of file
+			return false, fmt.Errorf("no moov atom found")
+		case 1:
+			if _, err := f.ReadAt(header[8:16], offset+8); err != nil {
+				return false, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+
+		if size < 8 {
+			return false, fmt.Errorf("invalid atom size %d at offset %d", size, offset)
+		}
+		offset += size
+	}
+}
@@ -0,0 +1,321 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MediaProbe holds the subset of ffprobe's JSON output used by the converter.
+type MediaProbe struct {
+	Streams  []ProbeStream  `json:"streams"`
+	Chapters []ProbeChapter `json:"chapters"`
+	Format   ProbeFormat    `json:"format"`
+}
+
+// ProbeStream describes a single stream reported by ffprobe.
+type ProbeStream struct {
+	Index            int               `json:"index"`
+	CodecName        string            `json:"codec_name"`
+	CodecType        string            `json:"codec_type"`
+	CodecTagString   string            `json:"codec_tag_string"`
+	Width            int               `json:"width"`
+	Height           int               `json:"height"`
+	PixelAspect      string            `json:"sample_aspect_ratio"`
+	PixFmt           string            `json:"pix_fmt"`
+	BitsPerRawSample string            `json:"bits_per_raw_sample"`
+	BitRate          string            `json:"bit_rate"`
+	RFrameRate       string            `json:"r_frame_rate"`
+	AvgFrameRate     string            `json:"avg_frame_rate"`
+	FieldOrder       string            `json:"field_order"`
+	Channels         int               `json:"channels"`
+	ChannelLayout    string            `json:"channel_layout"`
+	ColorRange       string            `json:"color_range"`
+	ColorSpace       string            `json:"color_space"`
+	ColorTransfer    string            `json:"color_transfer"`
+	ColorPrimaries   string            `json:"color_primaries"`
+	SideDataList     []ProbeSideData   `json:"side_data_list"`
+	Tags             map[string]string `json:"tags"`
+	Disposition      map[string]int    `json:"disposition"`
+}
+
+// ProbeSideData is a stream or frame side data entry. Values are strings or
+// numbers depending on the side data type.
+type ProbeSideData map[string]interface{}
+
+// Type returns the side_data_type of the entry.
+func (d ProbeSideData) Type() string {
+	return d.String("side_data_type")
+}
+
+// String returns a side data value formatted as a string.
+func (d ProbeSideData) String(key string) string {
+	switch value := d[key].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// ProbeChapter describes a chapter marker reported by ffprobe.
+type ProbeChapter struct {
+	ID        int64             `json:"id"`
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// ProbeFormat describes the container reported by ffprobe.
+type ProbeFormat struct {
+	FormatName string            `json:"format_name"`
+	Duration   string            `json:"duration"`
+	Size       string            `json:"size"`
+	BitRate    string            `json:"bit_rate"`
+	Tags       map[string]string `json:"tags"`
+}
+
+// ProbeMedia runs ffprobe once and returns the stream and format inventory.
+func ProbeMedia(filePath string) (*MediaProbe, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		"-show_chapters",
+		filePath,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe MediaProbe
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+
+	return &probe, nil
+}
+
+// VideoStream returns the primary video stream, ignoring embedded cover art.
+func (p *MediaProbe) VideoStream() *ProbeStream {
+	for i := range p.Streams {
+		stream := &p.Streams[i]
+		if stream.CodecType != "video" {
+			continue
+		}
+		if stream.Disposition["attached_pic"] == 1 {
+			continue
+		}
+		return stream
+	}
+	return nil
+}
+
+// StreamsOfType returns every stream of the given codec type (video, audio, subtitle, data).
+func (p *MediaProbe) StreamsOfType(codecType string) []ProbeStream {
+	var streams []ProbeStream
+	for _, stream := range p.Streams {
+		if stream.CodecType == codecType {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// StreamLayout counts the streams and chapters of a file, used to check that
+// nothing was dropped by a conversion.
+type StreamLayout struct {
+	Video     int
+	Audio     int
+	Subtitles int
+	Chapters  int
+}
+
+func (l StreamLayout) String() string {
+	return fmt.Sprintf("%d video, %d audio, %d subtitle, %d chapters", l.Video, l.Audio, l.Subtitles, l.Chapters)
+}
+
+// Layout counts the video (excluding cover art), audio and subtitle streams
+// and the chapters of the probed file.
+func (p *MediaProbe) Layout() StreamLayout {
+	layout := StreamLayout{Chapters: len(p.Chapters)}
+	for _, stream := range p.Streams {
+		switch stream.CodecType {
+		case "video":
+			if stream.Disposition["attached_pic"] != 1 {
+				layout.Video++
+			}
+		case "audio":
+			layout.Audio++
+		case "subtitle":
+			layout.Subtitles++
+		}
+	}
+	return layout
+}
+
+// DurationSeconds returns the container duration, or 0 when unknown.
+func (p *MediaProbe) DurationSeconds() float64 {
+	seconds, err := strconv.ParseFloat(p.Format.Duration, 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return seconds
+}
+
+// BitRate returns the overall bitrate in bits per second, or 0 when unknown.
+func (p *MediaProbe) BitRate() int64 {
+	bitrate, err := strconv.ParseInt(p.Format.BitRate, 10, 64)
+	if err != nil || bitrate < 0 {
+		return 0
+	}
+	return bitrate
+}
+
+// Tag looks up a container tag case-insensitively.
+func (p *MediaProbe) Tag(key string) string {
+	return lookupTag(p.Format.Tags, key)
+}
+
+// BitDepth returns the sample bit depth of the stream, derived from the
+// reported raw sample size or, failing that, from the pixel format name.
+func (s *ProbeStream) BitDepth() int {
+	if depth, err := strconv.Atoi(s.BitsPerRawSample); err == nil && depth > 0 {
+		return depth
+	}
+
+	pixFmt := strings.ToLower(s.PixFmt)
+	switch {
+	case strings.Contains(pixFmt, "16le"), strings.Contains(pixFmt, "16be"):
+		return 16
+	case strings.Contains(pixFmt, "12le"), strings.Contains(pixFmt, "12be"):
+		return 12
+	case strings.Contains(pixFmt, "10le"), strings.Contains(pixFmt, "10be"), pixFmt == "p010le":
+		return 10
+	case pixFmt == "":
+		return 0
+	default:
+		return 8
+	}
+}
+
+// BitRateValue returns the stream bitrate in bits per second, or 0 when unknown.
+func (s *ProbeStream) BitRateValue() int64 {
+	bitrate, err := strconv.ParseInt(s.BitRate, 10, 64)
+	if err != nil || bitrate < 0 {
+		return 0
+	}
+	return bitrate
+}
+
+// FrameRate returns the average frame rate, falling back to the base rate.
+func (s *ProbeStream) FrameRate() float64 {
+	if rate := ParseRational(s.AvgFrameRate); rate > 0 {
+		return rate
+	}
+	return ParseRational(s.RFrameRate)
+}
+
+// Interlaced reports whether ffprobe flags the stream as interlaced. An empty
+// or "unknown" field order is not conclusive and returns false.
+func (s *ProbeStream) Interlaced() bool {
+	switch s.FieldOrder {
+	case "tt", "bb", "tb", "bt":
+		return true
+	default:
+		return false
+	}
+}
+
+// Rotation returns the display rotation in degrees, normalised to 0, 90, 180
+// or 270. It is read from the display matrix side data or the legacy rotate tag.
+func (s *ProbeStream) Rotation() int {
+	degrees := 0
+	found := false
+	for _, entry := range s.SideDataList {
+		if entry.Type() == "Display Matrix" {
+			if value, err := strconv.ParseFloat(entry.String("rotation"), 64); err == nil {
+				degrees = int(math.Round(value))
+				found = true
+			}
+			break
+		}
+	}
+	if !found {
+		degrees, _ = strconv.Atoi(s.Tag("rotate"))
+	}
+
+	// The display matrix is counter-clockwise, the rotate tag clockwise
+	if found {
+		degrees = -degrees
+	}
+	return ((degrees % 360) + 360) % 360
+}
+
+// DisplaySize returns the frame size as shown to the viewer, with width and
+// height swapped for portrait rotations.
+func (s *ProbeStream) DisplaySize() (int, int) {
+	if rotation := s.Rotation(); rotation == 90 || rotation == 270 {
+		return s.Height, s.Width
+	}
+	return s.Width, s.Height
+}
+
+// DisplayAspectRatio returns the width/height ratio shown to the viewer,
+// including non-square pixels and rotation, or 0 when the size is unknown.
+func (s *ProbeStream) DisplayAspectRatio() float64 {
+	if s.Width <= 0 || s.Height <= 0 {
+		return 0
+	}
+	sar := ParseRational(strings.Replace(s.PixelAspect, ":", "/", 1))
+	if sar <= 0 {
+		sar = 1 // 0:1 or missing means square pixels
+	}
+	ratio := float64(s.Width) * sar / float64(s.Height)
+	if rotation := s.Rotation(); rotation == 90 || rotation == 270 {
+		return 1 / ratio
+	}
+	return ratio
+}
+
+// Tag looks up a stream tag case-insensitively.
+func (s *ProbeStream) Tag(key string) string {
+	return lookupTag(s.Tags, key)
+}
+
+func lookupTag(tags map[string]string, key string) string {
+	if value, ok := tags[key]; ok {
+		return value
+	}
+	for k, value := range tags {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ParseRational converts ffprobe ratios such as "30000/1001" to a float.
+func ParseRational(value string) float64 {
+	num, den, found := strings.Cut(strings.TrimSpace(value), "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}